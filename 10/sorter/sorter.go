@@ -0,0 +1,217 @@
+// Package sorter holds the line sorting of the sort tool, shared with the
+// sort builtin of the shell in 15/.
+package sorter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options select the sort key and how keys are compared.
+type Options struct {
+	Column         int
+	Numeric        bool
+	Reverse        bool
+	Unique         bool
+	Month          bool
+	IgnoreTrailing bool
+	HumanReadable  bool
+}
+
+var humanReadableSuffix = map[string]int64{
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+	"P": 1 << 50,
+	"E": 1 << 60,
+}
+
+var monthNames = map[string]time.Month{
+	"jan": time.January,
+	"feb": time.February,
+	"mar": time.March,
+	"apr": time.April,
+	"may": time.May,
+	"jun": time.June,
+	"jul": time.July,
+	"aug": time.August,
+	"sep": time.September,
+	"oct": time.October,
+	"nov": time.November,
+	"dec": time.December,
+}
+
+// ColumnValue returns the space-separated column of line, or the whole line
+// for column 0.
+func ColumnValue(line string, column int, ignoreTrailing bool) string {
+	if column == 0 {
+		if ignoreTrailing {
+			return strings.TrimRight(line, " \t")
+		}
+		return line
+	}
+
+	columns := strings.Split(line, " ")
+	if column > 0 && column <= len(columns) {
+		value := columns[column-1]
+		if ignoreTrailing {
+			return strings.TrimRight(value, " \t")
+		}
+		return value
+	}
+
+	return ""
+}
+
+// ParseMonth returns the number of a three-letter month name.
+func ParseMonth(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if month, ok := monthNames[s]; ok {
+		return int(month), nil
+	}
+	return 0, fmt.Errorf("invalid month: %s", s)
+}
+
+// ParseHumanReadable reads a size such as 512, 2K or 1MB.
+func ParseHumanReadable(s string) (int64, error) {
+	s = strings.TrimSpace(strings.ToUpper(s))
+
+	if num, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return num, nil
+	}
+
+	re := regexp.MustCompile(`^(\d+)([KMGTPE]?)B?$`)
+	matches := re.FindStringSubmatch(s)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid human readable format: %s", s)
+	}
+
+	num, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if suffix := matches[2]; suffix != "" {
+		multiplier, ok := humanReadableSuffix[suffix]
+		if !ok {
+			return 0, fmt.Errorf("unknow suffix: %s", suffix)
+		}
+		num *= multiplier
+	}
+
+	return num, nil
+}
+
+// Compare reports whether a sorts before b. Keys that do not parse as
+// numbers, months or sizes sort after those that do.
+func Compare(first, second string, opts Options) (bool, error) {
+	if opts.IgnoreTrailing {
+		first = strings.TrimRight(first, " \t")
+		second = strings.TrimRight(second, " \t")
+	}
+
+	if opts.Numeric {
+		numA, errA := strconv.ParseFloat(first, 64)
+		numB, errB := strconv.ParseFloat(second, 64)
+
+		if errA == nil && errB == nil {
+			return numA < numB, nil
+		} else if errA == nil {
+			return true, errA
+		} else if errB == nil {
+			return false, errB
+		}
+	}
+
+	if opts.Month {
+		monthA, errA := ParseMonth(first)
+		monthB, errB := ParseMonth(second)
+
+		if errA == nil && errB == nil {
+			return monthA < monthB, nil
+		} else if errA == nil {
+			return true, errA
+		} else if errB == nil {
+			return false, errB
+		}
+	}
+
+	if opts.HumanReadable {
+		sizeA, errA := ParseHumanReadable(first)
+		sizeB, errB := ParseHumanReadable(second)
+
+		if errA == nil && errB == nil {
+			return sizeA < sizeB, nil
+		} else if errA == nil {
+			return true, errA
+		} else if errB == nil {
+			return false, errB
+		}
+	}
+
+	return first < second, nil
+}
+
+// IsSorted reports whether lines are already in order.
+func IsSorted(lines []string, opts Options) bool {
+	for i := 1; i < len(lines); i++ {
+		prevVal := ColumnValue(lines[i-1], opts.Column, opts.IgnoreTrailing)
+		curVal := ColumnValue(lines[i], opts.Column, opts.IgnoreTrailing)
+
+		less, err := Compare(prevVal, curVal, opts)
+		if err != nil {
+			continue
+		}
+
+		if opts.Reverse {
+			if less {
+				return false
+			}
+		} else {
+			if !less && prevVal != curVal {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// RemoveDuplicates drops repeated lines, keeping the first of each.
+func RemoveDuplicates(lines []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0)
+
+	for _, line := range lines {
+		if !seen[line] {
+			seen[line] = true
+			result = append(result, line)
+		}
+	}
+
+	return result
+}
+
+// Sort sorts lines in place, keeping the order of equal keys.
+func Sort(lines []string, opts Options) {
+	sort.SliceStable(lines, func(i, j int) bool {
+		valA := ColumnValue(lines[i], opts.Column, opts.IgnoreTrailing)
+		valB := ColumnValue(lines[j], opts.Column, opts.IgnoreTrailing)
+
+		less, err := Compare(valA, valB, opts)
+		if err != nil {
+			return lines[i] < lines[j]
+		}
+
+		if opts.Reverse {
+			return !less
+		}
+
+		return less
+	})
+}
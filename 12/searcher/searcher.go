@@ -0,0 +1,63 @@
+// Package searcher holds the line matching of the grep tool, shared with the
+// grep builtin of the shell in 15/.
+package searcher
+
+import "strings"
+
+// Options select the matching lines and the context printed around them.
+type Options struct {
+	After   int
+	Before  int
+	Around  int
+	Ignore  bool
+	Inverse bool
+	Fix     bool
+}
+
+// Match reports whether line is selected by substr: it contains substr, or
+// equals it with Fix, ignoring case with Ignore and inverted with Inverse.
+func Match(line, substr string, opts Options) bool {
+	if opts.Ignore {
+		line = strings.ToLower(line)
+		substr = strings.ToLower(substr)
+	}
+
+	var founded bool
+	if opts.Fix {
+		founded = substr == line
+	} else {
+		founded = strings.Contains(line, substr)
+	}
+	return founded != opts.Inverse
+}
+
+// Search returns the indexes of the selected lines together with their
+// context lines, each once and in order.
+func Search(lines []string, substr string, opts Options) []int {
+	left := max(opts.Before, opts.Around)
+	right := max(opts.After, opts.Around)
+
+	res := make([]int, 0)
+	last := -1
+	for i, line := range lines {
+		if !Match(line, substr, opts) {
+			continue
+		}
+		for j := max(i-left, last+1); j <= min(i+right, len(lines)-1); j++ {
+			res = append(res, j)
+			last = j
+		}
+	}
+	return res
+}
+
+// Count returns the number of selected lines.
+func Count(lines []string, substr string, opts Options) int {
+	res := 0
+	for _, line := range lines {
+		if Match(line, substr, opts) {
+			res++
+		}
+	}
+	return res
+}
@@ -0,0 +1,48 @@
+package searcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		line   string
+		substr string
+		opts   Options
+		want   bool
+	}{
+		{"cab", "ab", Options{}, true},
+		{"cAb", "ab", Options{}, false},
+		{"cAb", "ab", Options{Ignore: true}, true},
+		{"cab", "ab", Options{Fix: true}, false},
+		{"AB", "ab", Options{Fix: true, Ignore: true}, true},
+		{"cab", "ab", Options{Inverse: true}, false},
+		{"bg", "ab", Options{Inverse: true}, true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, Match(test.line, test.substr, test.opts), "%s %+v", test.line, test.opts)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	lines := []string{"ab", "x", "y", "z", "ab", "w"}
+
+	tests := []struct {
+		opts Options
+		want []int
+	}{
+		{Options{}, []int{0, 4}},
+		{Options{Before: 1}, []int{0, 3, 4}},
+		{Options{After: 1}, []int{0, 1, 4, 5}},
+		{Options{Around: 2}, []int{0, 1, 2, 3, 4, 5}},
+		{Options{Before: 5}, []int{0, 1, 2, 3, 4}},
+		{Options{Inverse: true}, []int{1, 2, 3, 5}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, Search(lines, "ab", test.opts), "%+v", test.opts)
+	}
+}
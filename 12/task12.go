@@ -1,13 +1,13 @@
 package main
 
 import (
+	"L2/12/searcher"
 	"bufio"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"strconv"
-	"strings"
 
 	"github.com/spf13/pflag"
 )
@@ -23,69 +23,25 @@ type searchOptions struct {
 	number  bool
 }
 
+func (opts searchOptions) searcher() searcher.Options {
+	return searcher.Options{
+		After:   opts.after,
+		Before:  opts.before,
+		Around:  opts.around,
+		Ignore:  opts.ignore,
+		Inverse: opts.inverse,
+		Fix:     opts.fix,
+	}
+}
+
 func searchLines(lines []string, substr string, opts searchOptions) []string {
 	res := make([]string, 0)
 
-	left := max(opts.before, opts.around)
-	right := max(opts.after, opts.around)
-	last := -1
-
-	for i, line := range lines {
-		if opts.ignore {
-			line = strings.ToLower(line)
-			substr = strings.ToLower(substr)
-		}
-
-		var founded bool
-
-		if opts.inverse {
-			if opts.fix {
-				founded = substr != line
-			} else {
-				founded = !strings.Contains(line, substr)
-			}
+	for _, i := range searcher.Search(lines, substr, opts.searcher()) {
+		if opts.number {
+			res = append(res, strconv.Itoa(i)+" "+lines[i])
 		} else {
-			if opts.fix {
-				founded = substr == line
-			} else {
-				founded = strings.Contains(line, substr)
-			}
-		}
-
-		if founded {
-			if left > 0 {
-				for j := 0; j < left; j++ {
-					if i-left+j <= last {
-						continue
-					}
-					if opts.number {
-						res = append(res, strconv.Itoa(i-left+j)+" "+lines[i-left+j])
-					} else {
-						res = append(res, lines[i-left+j])
-					}
-				}
-			}
-			if last < i {
-				if opts.number {
-					res = append(res, strconv.Itoa(i)+" "+lines[i])
-				} else {
-					res = append(res, lines[i])
-				}
-			}
-			if right > 0 {
-				for j := 1; j <= right && i+j < len(lines); j++ {
-					if last >= i+j {
-						continue
-					}
-					if opts.number {
-						res = append(res, strconv.Itoa(i+j)+" "+lines[i+j])
-					} else {
-						res = append(res, lines[i+j])
-					}
-				}
-			}
-
-			last = i + right
+			res = append(res, lines[i])
 		}
 	}
 
@@ -93,36 +49,7 @@ func searchLines(lines []string, substr string, opts searchOptions) []string {
 }
 
 func countLines(lines []string, substr string, ignore, inverse, fix bool) int {
-	res := 0
-
-	for _, line := range lines {
-		if ignore {
-			line = strings.ToLower(line)
-			substr = strings.ToLower(substr)
-		}
-
-		var founded bool
-
-		if inverse {
-			if fix {
-				founded = substr != line
-			} else {
-				founded = !strings.Contains(line, substr)
-			}
-		} else {
-			if fix {
-				founded = substr == line
-			} else {
-				founded = strings.Contains(line, substr)
-			}
-		}
-
-		if founded {
-			res++
-		}
-	}
-
-	return res
+	return searcher.Count(lines, substr, searcher.Options{Ignore: ignore, Inverse: inverse, Fix: fix})
 }
 
 func readLines(inputFile string) ([]string, error) {
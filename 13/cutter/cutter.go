@@ -0,0 +1,86 @@
+// Package cutter holds the field selection of the cut tool, shared with the
+// cut builtin of the shell in 15/.
+package cutter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Range is an inclusive range of 1-based field numbers.
+type Range struct {
+	From int
+	To   int
+}
+
+// Fields is a list of fields such as "1,3-5,7-".
+type Fields []Range
+
+// Open is the end of a range such as "7-" that runs to the last field.
+const Open = int(^uint(0) >> 1)
+
+// ParseFields parses a comma-separated list of field numbers and ranges.
+// A range may leave out its start or its end.
+func ParseFields(list string) (Fields, error) {
+	if list == "" {
+		return nil, errors.New("you must specify a list of fields")
+	}
+
+	var res Fields
+	for _, item := range strings.Split(list, ",") {
+		from, to, isRange := strings.Cut(item, "-")
+		r := Range{From: 1, To: Open}
+
+		var err error
+		if from != "" {
+			r.From, err = strconv.Atoi(from)
+		}
+		if err == nil && isRange && to != "" {
+			r.To, err = strconv.Atoi(to)
+		} else if err == nil && !isRange {
+			r.To = r.From
+		}
+		if err != nil || r.From < 1 || r.To < r.From || (from == "" && to == "") {
+			return nil, fmt.Errorf("invalid field list: %s", list)
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// Indexes returns the 0-based indexes of the fields of a line with n
+// fields, in the order they are listed. It stops at the first field the
+// line does not have.
+func (f Fields) Indexes(n int) []int {
+	res := make([]int, 0)
+	for _, r := range f {
+		for i := r.From; i <= r.To; i++ {
+			if i > n {
+				return res
+			}
+			res = append(res, i-1)
+		}
+	}
+	return res
+}
+
+// Cut returns the fields of line at indexes, in the order given and up to
+// the first one the line does not have. It reports false if line does not
+// contain the delimiter.
+func Cut(line, delimiter string, indexes []int) ([]string, bool) {
+	if !strings.Contains(line, delimiter) {
+		return nil, false
+	}
+
+	parts := strings.Split(line, delimiter)
+	res := make([]string, 0)
+	for _, i := range indexes {
+		if i >= len(parts) {
+			break
+		}
+		res = append(res, parts[i])
+	}
+	return res, true
+}
@@ -0,0 +1,72 @@
+package cutter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		input   string
+		output  Fields
+		wantErr bool
+	}{
+		{"1", Fields{{1, 1}}, false},
+		{"1,3,5", Fields{{1, 1}, {3, 3}, {5, 5}}, false},
+		{"1-5", Fields{{1, 5}}, false},
+		{"1-3,5", Fields{{1, 3}, {5, 5}}, false},
+		{"1,2-3,5", Fields{{1, 1}, {2, 3}, {5, 5}}, false},
+		{"3-", Fields{{3, Open}}, false},
+		{"-2", Fields{{1, 2}}, false},
+		{"", Fields(nil), true},
+		{"a", Fields(nil), true},
+		{"0", Fields(nil), true},
+		{"3-1", Fields(nil), true},
+		{"-", Fields(nil), true},
+	}
+
+	for _, test := range tests {
+		res, err := ParseFields(test.input)
+		assert.Equal(t, test.output, res, test.input)
+		assert.Equal(t, test.wantErr, err != nil, test.input)
+	}
+}
+
+func TestIndexes(t *testing.T) {
+	tests := []struct {
+		fields Fields
+		n      int
+		want   []int
+	}{
+		{Fields{{1, 1}, {3, Open}}, 4, []int{0, 2, 3}},
+		{Fields{{3, 3}, {1, 1}}, 4, []int{2, 0}},
+		{Fields{{2, 4}}, 3, []int{1, 2}},
+		{Fields{{5, 5}, {1, 1}}, 4, []int{}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, test.fields.Indexes(test.n), "%v %d", test.fields, test.n)
+	}
+}
+
+func TestCut(t *testing.T) {
+	tests := []struct {
+		line    string
+		indexes []int
+		output  []string
+		ok      bool
+	}{
+		{"a:b:c:d", []int{0, 2, 3}, []string{"a", "c", "d"}, true},
+		{"a:b:c:d", []int{2, 0}, []string{"c", "a"}, true},
+		{"a:b:c", []int{0, 4, 1}, []string{"a"}, true},
+		{"a:b", []int{2, 3}, []string{}, true},
+		{"none", []int{0}, nil, false},
+	}
+
+	for _, test := range tests {
+		res, ok := Cut(test.line, ":", test.indexes)
+		assert.Equal(t, test.output, res, test.line)
+		assert.Equal(t, test.ok, ok, test.line)
+	}
+}
@@ -1,14 +1,12 @@
 package main
 
 import (
+	"L2/13/cutter"
 	"bufio"
-	"errors"
 	"fmt"
 	"log"
 	"os"
-	"strconv"
 	"strings"
-	"unicode"
 
 	"github.com/spf13/pflag"
 )
@@ -38,92 +36,29 @@ func readLines() ([]string, error) {
 	return lines, nil
 }
 
-func parseFields(fields string) ([]int, error) {
-	res := make([]int, 0)
-	prevNum := ""
-	curNum := ""
-	for i := range fields {
-		if unicode.IsDigit(rune(fields[i])) {
-			curNum += string(fields[i])
-		} else if fields[i] == ',' {
-			if prevNum == "" {
-				digit, err := strconv.Atoi(curNum)
-				if err != nil {
-					return nil, err
-				}
-				res = append(res, digit-1)
-			} else {
-				prevDigit, err := strconv.Atoi(prevNum)
-				if err != nil {
-					return nil, err
-				}
-				lastDigit, err := strconv.Atoi(curNum)
-				if err != nil {
-					return nil, err
-				}
-
-				for j := prevDigit; j <= lastDigit; j++ {
-					res = append(res, j-1)
-				}
-			}
-			prevNum = ""
-			curNum = ""
-		} else if fields[i] == '-' {
-			prevNum = curNum
-			curNum = ""
-		} else {
-			return nil, errors.New("invalid field")
-		}
+// parseFields returns the 0-based indexes of the listed fields. Every range
+// needs an end, as the fields are chosen before any line is read.
+func parseFields(list string) ([]int, error) {
+	fields, err := cutter.ParseFields(list)
+	if err != nil {
+		return nil, err
 	}
-
-	if prevNum == "" {
-		digit, err := strconv.Atoi(curNum)
-		if err != nil {
-			return nil, err
-		}
-		res = append(res, digit-1)
-	} else {
-		prevDigit, err := strconv.Atoi(prevNum)
-		if err != nil {
-			return nil, err
-		}
-		lastDigit, err := strconv.Atoi(curNum)
-		if err != nil {
-			return nil, err
+	for _, r := range fields {
+		if r.To == cutter.Open {
+			return nil, fmt.Errorf("invalid field list: %s", list)
 		}
-
-		for j := prevDigit; j <= lastDigit; j++ {
-			res = append(res, j-1)
-		}
-	}
-
-	if len(res) == 0 {
-		return nil, errors.New("need fields")
 	}
-
-	return res, nil
+	return fields.Indexes(cutter.Open), nil
 }
 
 func cutLines(lines []string, opts *cutOptions) [][]string {
 	res := make([][]string, 0)
 
 	for _, line := range lines {
-		if strings.Contains(line, opts.delimiter) {
-			parts := strings.Split(line, opts.delimiter)
-			resParts := make([]string, 0)
-
-			for _, field := range opts.fields {
-				if field >= len(parts) {
-					break
-				}
-				resParts = append(resParts, parts[field])
-			}
-
-			res = append(res, resParts)
-		} else {
-			if !opts.separated {
-				res = append(res, []string{line})
-			}
+		if parts, ok := cutter.Cut(line, opts.delimiter, opts.fields); ok {
+			res = append(res, parts)
+		} else if !opts.separated {
+			res = append(res, []string{line})
 		}
 	}
 
@@ -156,9 +91,6 @@ func main() {
 	res := cutLines(lines, &opts)
 
 	for _, line := range res {
-		for i := range len(line) - 1 {
-			fmt.Print(line[i] + opts.delimiter)
-		}
-		fmt.Print(line[len(line)-1] + "\n")
+		fmt.Println(strings.Join(line, opts.delimiter))
 	}
 }
@@ -46,3 +46,25 @@ func TestCutLines(t *testing.T) {
 		assert.Equal(t, test.output, res)
 	}
 }
+
+func TestCutLinesOrder(t *testing.T) {
+	tests := []struct {
+		fields string
+		input  []string
+		output [][]string
+	}{
+		{"3,1", []string{"ab,cd,ef"}, [][]string{{"ef", "ab"}}},
+		{"1,4,2", []string{"ab,cd,ef"}, [][]string{{"ab"}}},
+		{"2-3,1", []string{"ab,cd,ef", "ab,cd"}, [][]string{{"cd", "ef", "ab"}, {"cd"}}},
+	}
+
+	for _, test := range tests {
+		fields, err := parseFields(test.fields)
+		assert.NoError(t, err, test.fields)
+		res := cutLines(test.input, &cutOptions{fields: fields, delimiter: ","})
+		assert.Equal(t, test.output, res, test.fields)
+	}
+
+	_, err := parseFields("2-")
+	assert.Error(t, err, "open range")
+}
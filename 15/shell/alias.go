@@ -0,0 +1,125 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func (sh *shell) alias(args []string, std *stdio) error {
+	if len(args) == 0 {
+		names := make([]string, 0, len(sh.aliases))
+		for name := range sh.aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(std.out(), "alias %s=%s\n", name, shellQuote(sh.aliases[name]))
+		}
+		return nil
+	}
+
+	var err error
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			if value, ok := sh.aliases[name]; ok {
+				fmt.Fprintf(std.out(), "alias %s=%s\n", name, shellQuote(value))
+			} else {
+				fmt.Fprintf(std.err(), "alias: %s: not found\n", name)
+				err = &statusError{code: 1}
+			}
+			continue
+		}
+
+		if name == "" || strings.ContainsAny(name, " \t\n;&|()<>'\"\\$`=/") {
+			fmt.Fprintf(std.err(), "alias: `%s': invalid alias name\n", name)
+			err = &statusError{code: 1}
+			continue
+		}
+		if sh.aliases == nil {
+			sh.aliases = make(map[string]string)
+		}
+		sh.aliases[name] = value
+	}
+	return err
+}
+
+func (sh *shell) unalias(args []string) error {
+	if len(args) == 0 {
+		return errors.New("unalias: usage: unalias [-a] name [name ...]")
+	}
+	if args[0] == "-a" {
+		sh.aliases = nil
+		return nil
+	}
+
+	for _, name := range args {
+		if _, ok := sh.aliases[name]; !ok {
+			return fmt.Errorf("unalias: %s: not found", name)
+		}
+		delete(sh.aliases, name)
+	}
+	return nil
+}
+
+// expandAlias replaces an alias name at the start of a simple command with
+// the tokens of its value. An alias is not expanded again within its own
+// expansion, so `alias ls='ls -F'` does not recurse.
+func (p *parser) expandAlias() error {
+	expanded := make(map[string]bool)
+
+	for {
+		tok := p.peek()
+		if tok.kind != tokenWord {
+			return nil
+		}
+		name, ok := tok.word.literal()
+		if !ok || expanded[name] {
+			return nil
+		}
+		value, ok := p.aliases[name]
+		if !ok {
+			return nil
+		}
+		expanded[name] = true
+
+		tokens, err := tokenizeWithAliases(value, p.aliases)
+		if err != nil {
+			return err
+		}
+		tokens = tokens[:len(tokens)-1]
+		for i := range tokens {
+			tokens[i].pos = tok.pos
+			tokens[i].end = tok.end
+		}
+
+		rest := append(tokens, p.tokens[p.pos+1:]...)
+		p.tokens = append(p.tokens[:p.pos], rest...)
+	}
+}
+
+func (sh *shell) rcPath() string {
+	home := sh.homeDir()
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".l2shrc")
+}
+
+func (sh *shell) loadRC() error {
+	path := sh.rcPath()
+	if path == "" {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+
+	err := reportError(sh.source([]string{path}), sh.std)
+	sh.status = exitCode(err)
+	return err
+}
@@ -0,0 +1,99 @@
+package shell
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAlias(t *testing.T) {
+	aliases := map[string]string{
+		"ll":    "ls -l",
+		"ls":    "ls -F",
+		"both":  "echo a; echo b",
+		"loop":  "loop again",
+		"quiet": "",
+	}
+
+	tests := []struct {
+		input string
+		want  [][]string
+	}{
+		{"ll /tmp", [][]string{{"ls", "-F", "-l", "/tmp"}}},
+		{"echo ll", [][]string{{"echo", "ll"}}},
+		{"'ll' x", [][]string{{"ll", "x"}}},
+		{"X=1 ll", [][]string{{"ls", "-F", "-l"}}},
+		{"both; ll", [][]string{{"echo", "a"}, {"echo", "b"}, {"ls", "-F", "-l"}}},
+		{"true && ll | ll", [][]string{{"true"}, {"ls", "-F", "-l"}, {"ls", "-F", "-l"}}},
+		{"loop", [][]string{{"loop", "again"}}},
+		{"quiet echo x", [][]string{{"echo", "x"}}},
+	}
+
+	for _, test := range tests {
+		list, err := parseWithAliases(test.input, aliases)
+		assert.NoError(t, err, test.input)
+
+		var res [][]string
+		for _, item := range list.items {
+			for _, c := range item.commands {
+				for _, cmd := range c.pipeline.commands {
+					var args []string
+					for _, arg := range cmd.(*simpleCommand).args {
+						args = append(args, arg.String())
+					}
+					res = append(res, args)
+				}
+			}
+		}
+		assert.Equal(t, test.want, res, test.input)
+	}
+}
+
+func TestAliasBuiltins(t *testing.T) {
+	tests := []struct {
+		script string
+		out    string
+		errOut string
+		code   int
+	}{
+		{"alias e='echo hi'\ne there", "hi there\n", "", 0},
+		{"alias b=x a='y z'\nalias\nalias a", "alias a='y z'\nalias b=x\nalias a='y z'\n", "", 0},
+		{"alias nope", "", "alias: nope: not found\n", 1},
+		{"alias 'a b=x'", "", "alias: `a b': invalid alias name\n", 1},
+		{"alias e=echo\nunalias e\ne x", "", "e: command not found\n", 127},
+		{"alias a=x b=y\nunalias -a\nalias", "", "", 0},
+		{"unalias nope", "", "unalias: nope: not found\n", 1},
+		{"alias e=echo\n(e sub)\necho $(e subst)", "sub\nsubst\n", "", 0},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		sh := newTestShell(&out, &errOut)
+
+		err := sh.runInput(lineReader(strings.NewReader(test.script)), false)
+		assert.Equal(t, test.code, exitCode(err), test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Equal(t, test.errOut, errOut.String(), test.script)
+	}
+}
+
+func TestLoadRC(t *testing.T) {
+	home := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(home, ".l2shrc"), []byte("alias greet='echo hello'\nGREETING=set\n"), 0644))
+
+	var out, errOut bytes.Buffer
+	sh := newTestShell(&out, &errOut)
+	sh.setVar("HOME", home)
+
+	assert.NoError(t, sh.loadRC())
+	assert.NoError(t, sh.runInput(lineReader(strings.NewReader("greet $GREETING")), false))
+	assert.Equal(t, "hello set\n", out.String())
+	assert.Empty(t, errOut.String())
+
+	sh.setVar("HOME", t.TempDir())
+	assert.NoError(t, sh.loadRC())
+}
@@ -0,0 +1,115 @@
+package shell
+
+type commandList struct {
+	items []*listItem
+}
+
+type listItem struct {
+	commands   []conditionalCommand
+	background bool
+	text       string
+}
+
+type pipeline struct {
+	commands []command
+	negate   bool
+	text     string
+}
+
+type command interface {
+	redirects() []*redirection
+}
+
+type simpleCommand struct {
+	assigns []*assignment
+	args    []*word
+	redirs  []*redirection
+}
+
+type assignment struct {
+	name  string
+	value *word
+}
+
+type subshell struct {
+	body   *commandList
+	redirs []*redirection
+}
+
+type braceGroup struct {
+	body   *commandList
+	redirs []*redirection
+}
+
+type ifClause struct {
+	branches []ifBranch
+	elseBody *commandList
+	redirs   []*redirection
+}
+
+type ifBranch struct {
+	condition *commandList
+	body      *commandList
+}
+
+type loopClause struct {
+	until     bool
+	condition *commandList
+	body      *commandList
+	redirs    []*redirection
+}
+
+type forClause struct {
+	name   string
+	words  []*word
+	body   *commandList
+	redirs []*redirection
+}
+
+type caseClause struct {
+	subject *word
+	items   []*caseItem
+	redirs  []*redirection
+}
+
+type caseItem struct {
+	patterns []*word
+	body     *commandList
+}
+
+type functionDef struct {
+	name string
+	body command
+}
+
+func (c *simpleCommand) redirects() []*redirection {
+	return c.redirs
+}
+
+func (c *subshell) redirects() []*redirection {
+	return c.redirs
+}
+
+func (c *braceGroup) redirects() []*redirection {
+	return c.redirs
+}
+
+func (c *ifClause) redirects() []*redirection {
+	return c.redirs
+}
+
+func (c *loopClause) redirects() []*redirection {
+	return c.redirs
+}
+
+func (c *forClause) redirects() []*redirection {
+	return c.redirs
+}
+
+func (c *caseClause) redirects() []*redirection {
+	return c.redirs
+}
+
+func (c *functionDef) redirects() []*redirection {
+	return nil
+}
@@ -0,0 +1,183 @@
+package shell
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+func expandBraces(w *word) []*word {
+	expanded := braceParts(splitLiteral(w.parts))
+	res := make([]*word, 0, len(expanded))
+	for _, parts := range expanded {
+		res = append(res, &word{parts: joinLiteral(parts), raw: w.raw})
+	}
+	return res
+}
+
+func splitLiteral(parts []wordPart) []wordPart {
+	res := make([]wordPart, 0, len(parts))
+	for _, part := range parts {
+		if part.quoted || part.expands() || part.text == "" {
+			res = append(res, part)
+			continue
+		}
+		for _, r := range part.text {
+			res = append(res, wordPart{text: string(r)})
+		}
+	}
+	return res
+}
+
+func joinLiteral(parts []wordPart) []wordPart {
+	var res []wordPart
+	for _, part := range parts {
+		if n := len(res); n > 0 && !part.expands() && !res[n-1].expands() && part.quoted == res[n-1].quoted && part.text != "" {
+			res[n-1].text += part.text
+			continue
+		}
+		res = append(res, part)
+	}
+	return res
+}
+
+func isLiteralChar(part wordPart, c string) bool {
+	return !part.quoted && !part.expands() && part.text == c
+}
+
+func braceParts(parts []wordPart) [][]wordPart {
+	for i := range parts {
+		if !isLiteralChar(parts[i], "{") {
+			continue
+		}
+
+		alternatives, end, ok := braceAlternatives(parts, i)
+		if !ok {
+			continue
+		}
+
+		var res [][]wordPart
+		for _, alternative := range alternatives {
+			rest := append(append([]wordPart{}, alternative...), parts[end+1:]...)
+			for _, tail := range braceParts(rest) {
+				res = append(res, append(append([]wordPart{}, parts[:i]...), tail...))
+			}
+		}
+		return res
+	}
+
+	return [][]wordPart{parts}
+}
+
+func braceAlternatives(parts []wordPart, start int) ([][]wordPart, int, bool) {
+	var alternatives [][]wordPart
+	depth := 0
+	last := start + 1
+
+	for i := start + 1; i < len(parts); i++ {
+		switch {
+		case isLiteralChar(parts[i], "{"):
+			depth++
+		case isLiteralChar(parts[i], "}") && depth > 0:
+			depth--
+		case isLiteralChar(parts[i], "}"):
+			if len(alternatives) == 0 {
+				sequence, ok := braceSequence(parts[start+1 : i])
+				return sequence, i, ok
+			}
+			return append(alternatives, parts[last:i]), i, true
+		case isLiteralChar(parts[i], ",") && depth == 0:
+			alternatives = append(alternatives, parts[last:i])
+			last = i + 1
+		}
+	}
+
+	return nil, 0, false
+}
+
+func braceSequence(parts []wordPart) ([][]wordPart, bool) {
+	var sb strings.Builder
+	for _, part := range parts {
+		if part.quoted || part.expands() {
+			return nil, false
+		}
+		sb.WriteString(part.text)
+	}
+
+	bounds := strings.Split(sb.String(), "..")
+	if len(bounds) != 2 && len(bounds) != 3 {
+		return nil, false
+	}
+
+	step := 1
+	if len(bounds) == 3 {
+		n, err := strconv.Atoi(bounds[2])
+		if err != nil || n == 0 {
+			return nil, false
+		}
+		step = max(n, -n)
+	}
+
+	var items []string
+	if from, err := strconv.Atoi(bounds[0]); err == nil {
+		to, err := strconv.Atoi(bounds[1])
+		if err != nil {
+			return nil, false
+		}
+		width := 0
+		if isPadded(bounds[0]) || isPadded(bounds[1]) {
+			width = max(len(bounds[0]), len(bounds[1]))
+		}
+		for _, n := range sequence(from, to, step) {
+			items = append(items, padNumber(n, width))
+		}
+	} else {
+		from, fromSize := utf8.DecodeRuneInString(bounds[0])
+		to, toSize := utf8.DecodeRuneInString(bounds[1])
+		if fromSize != len(bounds[0]) || toSize != len(bounds[1]) || fromSize == 0 || toSize == 0 || isNumber(bounds[1]) {
+			return nil, false
+		}
+		for _, n := range sequence(int(from), int(to), step) {
+			items = append(items, string(rune(n)))
+		}
+	}
+
+	res := make([][]wordPart, 0, len(items))
+	for _, item := range items {
+		res = append(res, []wordPart{{text: item}})
+	}
+	return res, true
+}
+
+func sequence(from, to, step int) []int {
+	var res []int
+	if from <= to {
+		for n := from; n <= to; n += step {
+			res = append(res, n)
+		}
+	} else {
+		for n := from; n >= to; n -= step {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+func isPadded(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	return len(s) > 1 && s[0] == '0'
+}
+
+func padNumber(n, width int) string {
+	s := strconv.Itoa(max(n, -n))
+	if n < 0 {
+		width--
+	}
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+	if n < 0 {
+		s = "-" + s
+	}
+	return s
+}
@@ -0,0 +1,482 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func init() {
+	for _, b := range []*shellBuiltin{
+		{"cd", "cd [dir]\n\nChange the current directory to dir, $HOME by default. `cd -` returns\nto $OLDPWD.", func(sh *shell, args []string, std *stdio) error { return sh.cd(args[1:]) }},
+		{"pwd", "pwd\n\nPrint the current directory.", func(sh *shell, args []string, std *stdio) error { return sh.pwd(std) }},
+		{"echo", "echo [arg ...]\n\nWrite the arguments separated by spaces, followed by a newline.", func(sh *shell, args []string, std *stdio) error { return echo(args[1:], std) }},
+		{"kill", "kill [-s sig | -sig] pid | %job ...\n\nSend a signal, SIGTERM by default, to processes or jobs. `kill -l`\nlists the signal names.", func(sh *shell, args []string, std *stdio) error { return sh.kill(args[1:], std) }},
+		{"ps", "ps [-e] [-f] [-o format]\n\nList processes from /proc. -e selects all processes, -f the full\nformat, and -o a comma-separated list of columns: pid, ppid, uid, user,\nstate, cpu, rss, tty, time, comm and cmd.", func(sh *shell, args []string, std *stdio) error { return sh.ps(args[1:], std) }},
+		{"jobs", "jobs [job ...]\n\nList the background and stopped jobs.", func(sh *shell, args []string, std *stdio) error { return sh.listJobs(args[1:], std) }},
+		{"fg", "fg [job]\n\nMove a job to the foreground.", func(sh *shell, args []string, std *stdio) error { return sh.fg(args[1:], std) }},
+		{"bg", "bg [job]\n\nResume a stopped job in the background.", func(sh *shell, args []string, std *stdio) error { return sh.bg(args[1:], std) }},
+		{"wait", "wait [pid | job ...]\n\nWait for background jobs and return the status of the last one.", func(sh *shell, args []string, std *stdio) error { return sh.wait(args[1:]) }},
+		{"export", "export [name[=value] ...]\n\nMark variables for export to the environment of commands.", func(sh *shell, args []string, std *stdio) error { return sh.export(args[1:], std) }},
+		{"unset", "unset [-f | -v] [name ...]\n\nRemove variables, or functions with -f.", func(sh *shell, args []string, std *stdio) error { return sh.unset(args[1:]) }},
+		{"set", "set [-efux] [-o option] [--] [arg ...]\n\nSet shell options and positional parameters, or list the variables.", func(sh *shell, args []string, std *stdio) error { return sh.set(args[1:], std) }},
+		{"exit", "exit [n]\n\nExit the shell with status n, or with the status of the last command.", func(sh *shell, args []string, std *stdio) error { return sh.exit(args[1:], std) }},
+		{"source", "source file [arg ...]\n\nExecute the commands from file in the current shell.", func(sh *shell, args []string, std *stdio) error { return sh.source(args[1:]) }},
+		{".", ". file [arg ...]\n\nExecute the commands from file in the current shell.", func(sh *shell, args []string, std *stdio) error { return sh.source(args[1:]) }},
+		{"shift", "shift [n]\n\nShift the positional parameters left by n, 1 by default.", func(sh *shell, args []string, std *stdio) error { return sh.shift(args[1:]) }},
+		{"local", "local name[=value] ...\n\nCreate variables visible only in the current function.", func(sh *shell, args []string, std *stdio) error { return sh.local(args[1:]) }},
+		{"return", "return [n]\n\nReturn from a function or sourced file with status n.", func(sh *shell, args []string, std *stdio) error { return sh.returnFromFunction(args[1:]) }},
+		{"break", "break [n]\n\nExit from the n-th enclosing loop.", func(sh *shell, args []string, std *stdio) error { return sh.loopControl(args[0], args[1:], std) }},
+		{"continue", "continue [n]\n\nResume the next iteration of the n-th enclosing loop.", func(sh *shell, args []string, std *stdio) error { return sh.loopControl(args[0], args[1:], std) }},
+		{"test", "test expr\n\nEvaluate a conditional expression.", func(sh *shell, args []string, std *stdio) error { return testExpression(sh.dir, args) }},
+		{"[", "[ expr ]\n\nEvaluate a conditional expression.", func(sh *shell, args []string, std *stdio) error { return testExpression(sh.dir, args) }},
+		{":", ":\n\nDo nothing and succeed.", func(sh *shell, args []string, std *stdio) error { return nil }},
+		{"true", "true\n\nSucceed.", func(sh *shell, args []string, std *stdio) error { return nil }},
+		{"false", "false\n\nFail with status 1.", func(sh *shell, args []string, std *stdio) error { return &statusError{code: 1} }},
+		{"alias", "alias [name[=value] ...]\n\nDefine or print aliases.", func(sh *shell, args []string, std *stdio) error { return sh.alias(args[1:], std) }},
+		{"unalias", "unalias [-a] name ...\n\nRemove aliases.", func(sh *shell, args []string, std *stdio) error { return sh.unalias(args[1:]) }},
+		{"type", "type [-t] name ...\n\nDescribe how each name would be interpreted as a command.", func(sh *shell, args []string, std *stdio) error { return sh.typeBuiltin(args[1:], std) }},
+		{"which", "which name ...\n\nShow how each name resolves: alias, function, builtin or path.", func(sh *shell, args []string, std *stdio) error { return sh.which(args[1:], std) }},
+		{"command", "command [-vV] name [arg ...]\n\nRun a command bypassing shell functions, or describe it with -v/-V.", func(sh *shell, args []string, std *stdio) error { return sh.command(args[1:], std) }},
+		{"trap", "trap [-lp] [[action] signal ...]\n\nRun action when the shell receives a signal, or with EXIT when it exits.\nAn empty action ignores the signal and - resets it. -p prints the traps\nand -l lists the signal names.", func(sh *shell, args []string, std *stdio) error { return sh.trap(args[1:], std) }},
+		{"hash", "hash [-r] [-p path] [-dt] [name ...]\n\nRemember where commands are found in PATH, or list the remembered\ncommands and how often they ran. -r forgets all of them, -d the named\nones, -p sets the path of a name and -t prints it. The table is cleared\nwhenever PATH changes.", func(sh *shell, args []string, std *stdio) error { return sh.hashBuiltin(args[1:], std) }},
+		{"builtin", "builtin name [arg ...]\n\nRun a shell builtin, bypassing functions and executables.", func(sh *shell, args []string, std *stdio) error { return sh.builtin(args[1:], std) }},
+		{"help", "help [name ...]\n\nList the builtins, or show the help of the named ones.", func(sh *shell, args []string, std *stdio) error { return sh.help(args[1:], std) }},
+	} {
+		RegisterBuiltin(b)
+	}
+}
+
+func (sh *shell) identCommand(cmd *simpleCommand, std *stdio) error {
+	sh.substStatus = 0
+
+	args, err := sh.expandWords(cmd.args)
+	if err != nil {
+		return err
+	}
+
+	std, closeFiles, err := sh.applyRedirection(cmd.redirs, std)
+	if err != nil {
+		return err
+	}
+	defer closeFiles()
+
+	assigns, err := sh.expandAssignments(cmd.assigns)
+	if err != nil {
+		return err
+	}
+
+	sh.trace(cmd.assigns, assigns, args, std)
+
+	if len(args) == 0 {
+		for _, assign := range cmd.assigns {
+			sh.setVar(assign.name, assigns[assign.name])
+		}
+		if sh.substStatus != 0 {
+			return &statusError{code: sh.substStatus}
+		}
+		return nil
+	}
+
+	if fn, ok := sh.functions[args[0]]; ok {
+		return sh.withTemporaryVars(assigns, func() error {
+			return sh.callFunction(fn, args, std)
+		})
+	}
+
+	b, ok := sh.lookupBuiltin(args[0])
+	if !ok {
+		return reportError(sh.externalCommand(args, assigns, std), std)
+	}
+
+	return reportError(sh.withTemporaryVars(assigns, func() error {
+		return sh.runBuiltin(b, args, std)
+	}), std)
+}
+
+// expandAssignments expands assignments left to right, each one seeing the
+// values of those before it, and returns the final value of each name.
+func (sh *shell) expandAssignments(assigns []*assignment) (map[string]string, error) {
+	res := make(map[string]string, len(assigns))
+	saved := make(map[string]*variable, len(assigns))
+	defer func() {
+		for name, v := range saved {
+			if v == nil {
+				delete(sh.vars, name)
+			} else {
+				sh.vars[name] = v
+			}
+		}
+	}()
+
+	for _, assign := range assigns {
+		value, err := sh.expandString(&word{parts: sh.expandTilde(assign.value.parts, true)})
+		if err != nil {
+			return nil, err
+		}
+		res[assign.name] = value
+
+		old, ok := saved[assign.name]
+		if !ok {
+			old = sh.vars[assign.name]
+			saved[assign.name] = old
+		}
+		sh.vars[assign.name] = &variable{value: value, exported: old != nil && old.exported}
+	}
+	return res, nil
+}
+
+func (sh *shell) cd(args []string) error {
+	var path string
+
+	if len(args) == 0 {
+		path = sh.homeDir()
+	} else {
+		path = args[0]
+	}
+	if path == "-" {
+		path = sh.getVar("OLDPWD")
+		if path == "" {
+			return errors.New("cd: OLDPWD not set")
+		}
+	}
+
+	oldDir := sh.workDir()
+	dir := path
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(oldDir, dir)
+	}
+
+	info, err := os.Stat(dir)
+	if err == nil && !info.IsDir() {
+		err = syscall.ENOTDIR
+	} else if err == nil {
+		err = unix.Access(dir, unix.X_OK)
+	}
+	if err != nil {
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		return &os.PathError{Op: "chdir", Path: path, Err: err}
+	}
+
+	sh.dir = filepath.Clean(dir)
+	sh.setVar("OLDPWD", oldDir)
+	sh.setVar("PWD", sh.dir)
+
+	return nil
+}
+
+func (sh *shell) export(args []string, std *stdio) error {
+	if len(args) == 0 || args[0] == "-p" {
+		for _, entry := range sh.environ() {
+			name, value, _ := strings.Cut(entry, "=")
+			fmt.Fprintf(std.out(), "export %s=%s\n", name, shellQuote(value))
+		}
+		return nil
+	}
+
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !isName(name) {
+			return fmt.Errorf("export: `%s': not a valid identifier", arg)
+		}
+		if hasValue {
+			sh.setVar(name, value)
+		}
+		sh.exportVar(name)
+	}
+
+	return nil
+}
+
+// unset removes variables with -v and functions with -f. Without either, a
+// name that is not a variable removes the function of that name, as in bash.
+func (sh *shell) unset(args []string) error {
+	functions, variables := false, false
+	for len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-' {
+		arg := args[0]
+		args = args[1:]
+		if arg == "--" {
+			break
+		}
+		for _, c := range arg[1:] {
+			switch c {
+			case 'f':
+				functions = true
+			case 'v':
+				variables = true
+			default:
+				return fmt.Errorf("unset: -%c: invalid option", c)
+			}
+		}
+	}
+	if functions && variables {
+		return errors.New("unset: cannot simultaneously unset a function and a variable")
+	}
+
+	for _, name := range args {
+		if functions {
+			delete(sh.functions, name)
+			continue
+		}
+		if !isName(name) {
+			return fmt.Errorf("unset: `%s': not a valid identifier", name)
+		}
+		if _, ok := sh.vars[name]; ok || variables {
+			sh.unsetVar(name)
+		} else {
+			delete(sh.functions, name)
+		}
+	}
+	return nil
+}
+
+func (sh *shell) set(args []string, std *stdio) error {
+	if len(args) == 0 {
+		names := make([]string, 0, len(sh.vars))
+		for name := range sh.vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(std.out(), "%s=%s\n", name, shellQuote(sh.vars[name].value))
+		}
+		return nil
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			sh.args = append([]string(nil), args[i+1:]...)
+			return nil
+		}
+		if len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') {
+			sh.args = append([]string(nil), args[i:]...)
+			return nil
+		}
+		enable := arg[0] == '-'
+
+		if arg[1:] == "o" {
+			if i+1 == len(args) {
+				sh.listOptions(std, enable)
+				return nil
+			}
+			i++
+			if err := sh.setOption(args[i], enable); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, flag := range arg[1:] {
+			name := optionName(flag)
+			if name == "" {
+				return fmt.Errorf("set: %c%c: invalid option", arg[0], flag)
+			}
+			if err := sh.setOption(name, enable); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./:,=@%+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func (sh *shell) pwd(std *stdio) error {
+	_, err := fmt.Fprintln(std.out(), sh.workDir())
+	return err
+}
+
+func echo(args []string, std *stdio) error {
+	_, err := fmt.Fprintln(std.out(), strings.Join(args, " "))
+	return err
+}
+
+func (sh *shell) listJobs(args []string, std *stdio) error {
+	jobs := sh.jobs.list()
+	if len(args) > 0 {
+		jobs = nil
+		for _, arg := range args {
+			j, err := sh.jobs.find(arg)
+			if err != nil {
+				return fmt.Errorf("jobs: %v", err)
+			}
+			jobs = append(jobs, j)
+		}
+	}
+
+	for _, j := range jobs {
+		fmt.Fprintln(std.out(), sh.jobs.format(j))
+		if j.currentState() == jobDone {
+			sh.jobs.remove(j)
+		}
+	}
+
+	return nil
+}
+
+func (sh *shell) fg(args []string, std *stdio) error {
+	if !sh.interactive {
+		return errors.New("fg: no job control")
+	}
+
+	j, err := sh.jobs.find(strings.Join(args, " "))
+	if err != nil {
+		return fmt.Errorf("fg: %v", err)
+	}
+
+	fmt.Fprintln(std.out(), j.command)
+
+	sh.giveTerminal(j.processGroup())
+	j.setState(jobRunning)
+	err = j.signal(syscall.SIGCONT)
+	if err != nil {
+		return fmt.Errorf("fg: %v", err)
+	}
+
+	err = sh.waitForeground(j)
+	if j.currentState() == jobDone {
+		sh.jobs.remove(j)
+	}
+
+	return err
+}
+
+func (sh *shell) bg(args []string, std *stdio) error {
+	if !sh.interactive {
+		return errors.New("bg: no job control")
+	}
+
+	j, err := sh.jobs.find(strings.Join(args, " "))
+	if err != nil {
+		return fmt.Errorf("bg: %v", err)
+	}
+
+	j.setState(jobRunning)
+	err = j.signal(syscall.SIGCONT)
+	if err != nil {
+		return fmt.Errorf("bg: %v", err)
+	}
+
+	fmt.Fprintf(std.out(), "[%d]+ %s &\n", j.id, j.command)
+	return nil
+}
+
+func (sh *shell) wait(args []string) error {
+	if len(args) == 0 {
+		// Stopped jobs are skipped, as they may never finish.
+		for _, j := range sh.jobs.list() {
+			if waitRunning(j) {
+				sh.jobs.remove(j)
+			}
+		}
+		return nil
+	}
+
+	var err error
+	for _, arg := range args {
+		var j *job
+		if strings.HasPrefix(arg, "%") {
+			j, err = sh.jobs.find(arg)
+			if err != nil {
+				return fmt.Errorf("wait: %v", err)
+			}
+		} else {
+			pid, convErr := strconv.Atoi(arg)
+			if convErr != nil {
+				return fmt.Errorf("wait: `%s': not a pid or valid job spec", arg)
+			}
+			j = sh.jobs.findByPid(pid)
+			if j == nil {
+				return &statusError{code: 127}
+			}
+		}
+
+		<-j.done
+		sh.jobs.remove(j)
+		err = j.err
+	}
+
+	return err
+}
+
+func (sh *shell) lookPath(name string) (string, error) {
+	if strings.Contains(name, "/") {
+		if isExecutable(sh.path(name)) {
+			return name, nil
+		}
+		return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+
+	if path, ok := sh.hashed(name); ok {
+		return path, nil
+	}
+
+	// Commands found through relative PATH entries are not remembered, as
+	// their path depends on the working directory.
+	for _, dir := range filepath.SplitList(sh.getVar("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+		path := filepath.Join(dir, name)
+		if isExecutable(sh.path(path)) {
+			if filepath.IsAbs(path) {
+				sh.remember(name, path)
+			}
+			return path, nil
+		}
+	}
+
+	return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode()&0111 != 0
+}
+
+func (sh *shell) newCommand(args []string, env []string) *exec.Cmd {
+	cmd := &exec.Cmd{Path: args[0], Args: args, Env: env, Dir: sh.dir}
+
+	// A path is run even if it is not executable, so that starting it
+	// reports why.
+	path, err := sh.lookPath(args[0])
+	if err == nil {
+		cmd.Path = path
+		sh.hashHit(args[0])
+	} else if !strings.Contains(args[0], "/") {
+		cmd.Err = err
+	}
+
+	return cmd
+}
+
+func (sh *shell) externalCommand(args []string, assigns map[string]string, std *stdio) error {
+	cmd := sh.newCommand(args, sh.commandEnv(assigns))
+	if cmd.Err == nil && isShellScript(sh.path(cmd.Path)) {
+		return sh.runShellScript(cmd.Path, args[1:], cmd.Env, std)
+	}
+
+	std.attach(cmd)
+
+	return sh.runProcesses([]*exec.Cmd{cmd}, strings.Join(args, " "), nil, func(errs []error) error {
+		return errs[0]
+	})
+}
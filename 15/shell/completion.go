@@ -0,0 +1,109 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func (sh *shell) complete(line []rune, pos int) (int, []string) {
+	start := pos
+	for start > 0 {
+		c := line[start-1]
+		if (c == ' ' || c == '\t' || strings.ContainsRune(";&|()<>", c)) && (start < 2 || line[start-2] != '\\') {
+			break
+		}
+		start--
+	}
+
+	word := unescapeWord(string(line[start:pos]))
+	before := strings.TrimSpace(string(line[:start]))
+	commandPosition := before == "" || strings.ContainsAny(before[len(before)-1:], ";&|(")
+
+	if commandPosition && !strings.Contains(word, "/") {
+		return start, completeCommands(word, sh.getVar("PATH"))
+	}
+
+	return start, completeFiles(sh.workDir(), word, sh.homeDir(), commandPosition)
+}
+
+func completeCommands(prefix, path string) []string {
+	seen := make(map[string]bool)
+	var res []string
+
+	add := func(name string) {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			res = append(res, name)
+		}
+	}
+
+	for _, name := range builtinNames() {
+		add(name)
+	}
+
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !strings.HasPrefix(entry.Name(), prefix) || entry.IsDir() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			if info.Mode()&0111 != 0 || info.Mode()&os.ModeSymlink != 0 {
+				add(entry.Name())
+			}
+		}
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+func completeFiles(workDir, word, home string, executablesOnly bool) []string {
+	dir, base := filepath.Split(word)
+
+	lookup := dir
+	if strings.HasPrefix(lookup, "~/") && home != "" {
+		lookup = home + strings.TrimPrefix(lookup, "~")
+	}
+	if !filepath.IsAbs(lookup) {
+		lookup = filepath.Join(workDir, lookup)
+	}
+
+	entries, err := os.ReadDir(lookup)
+	if err != nil {
+		return nil
+	}
+
+	var res []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+
+		info, err := os.Stat(filepath.Join(lookup, name))
+		if err != nil {
+			continue
+		}
+
+		if info.IsDir() {
+			res = append(res, dir+name+"/")
+		} else if !executablesOnly || info.Mode()&0111 != 0 {
+			res = append(res, dir+name)
+		}
+	}
+
+	sort.Strings(res)
+	return res
+}
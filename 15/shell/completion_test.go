@@ -0,0 +1,48 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplete(t *testing.T) {
+	bin := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(bin, "mytool"), []byte("#!/bin/sh\n"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(bin, "mydata"), []byte(""), 0644))
+
+	dir := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "src"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), nil, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "make.sh"), nil, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), nil, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "src", "lib.go"), nil, 0644))
+	t.Chdir(dir)
+
+	sh := &shell{jobs: &jobTable{}, vars: environVars([]string{"PATH=" + bin})}
+
+	tests := []struct {
+		line  string
+		start int
+		want  []string
+	}{
+		{"my", 0, []string{"mytool"}},
+		{"ec", 0, []string{"echo"}},
+		{"ls | my", 5, []string{"mytool"}},
+		{"cat m", 4, []string{"main.go", "make.sh"}},
+		{"cat s", 4, []string{"src/"}},
+		{"cat src/l", 4, []string{"src/lib.go"}},
+		{"cat .h", 4, []string{".hidden"}},
+		{"./m", 0, []string{"./make.sh"}},
+		{"cat zz", 4, nil},
+	}
+
+	for _, test := range tests {
+		line := []rune(test.line)
+		start, res := sh.complete(line, len(line))
+		assert.Equal(t, test.start, start, test.line)
+		assert.Equal(t, test.want, res, test.line)
+	}
+}
@@ -0,0 +1,154 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+type conditionalCommand struct {
+	pipeline *pipeline
+	operator string
+}
+
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	if e.message != "" {
+		return e.message
+	}
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	if status, ok := signalStatus(err); ok {
+		return 128 + int(status.Signal())
+	}
+
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) {
+		return exitError.ExitCode()
+	}
+
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.code
+	}
+
+	var exit *shellExit
+	if errors.As(err, &exit) {
+		return exit.code
+	}
+
+	var ret *functionReturn
+	if errors.As(err, &ret) {
+		return ret.code
+	}
+
+	var loop *loopControl
+	if errors.As(err, &loop) {
+		return 0
+	}
+
+	return 1
+}
+
+func (sh *shell) executeList(list *commandList, std *stdio) error {
+	var err error
+	for _, item := range list.items {
+		if item.background {
+			sh.startBackground(item, std)
+			err = nil
+			continue
+		}
+		err = sh.executeConditionalCommands(item.commands, std)
+		if isControlFlow(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (sh *shell) executeConditionalCommands(cmds []conditionalCommand, std *stdio) error {
+	var err error
+
+	for i, c := range cmds {
+		if i > 0 {
+			operator := cmds[i-1].operator
+			if operator == "&&" && err != nil || operator == "||" && err == nil {
+				continue
+			}
+		}
+
+		if canceled := sh.canceled(); canceled != nil {
+			return canceled
+		}
+		err = sh.executePipeline(c.pipeline, std)
+		if canceled := sh.canceled(); canceled != nil {
+			return canceled
+		}
+		if trapErr := sh.runTraps(); trapErr != nil {
+			return trapErr
+		}
+
+		var expansion *expansionError
+		fatal := errors.As(err, &expansion) && !sh.interactive
+
+		err = reportError(err, std)
+		if c.pipeline.negate && !isControlFlow(err) && !fatal {
+			err = negate(err)
+		}
+		sh.status = exitCode(err)
+
+		if isControlFlow(err) {
+			return err
+		}
+		if fatal {
+			return &shellExit{code: sh.status}
+		}
+		if err != nil && i == len(cmds)-1 && !c.pipeline.negate && sh.options["errexit"] && sh.conditionDepth == 0 {
+			return &shellExit{code: sh.status}
+		}
+	}
+
+	return err
+}
+
+func negate(err error) error {
+	if err == nil {
+		return &statusError{code: 1}
+	}
+	return nil
+}
+
+func reportError(err error, std *stdio) error {
+	if err == nil {
+		return nil
+	}
+
+	var status *statusError
+	if errors.As(err, &status) && status.message != "" {
+		fmt.Fprintln(std.err(), status.message)
+		return &statusError{code: status.code}
+	}
+
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) || status != nil || isControlFlow(err) {
+		return err
+	}
+
+	if errors.Is(err, syscall.EPIPE) {
+		return &statusError{code: 128 + int(syscall.SIGPIPE)}
+	}
+
+	fmt.Fprintln(std.err(), err)
+	return &statusError{code: 1}
+}
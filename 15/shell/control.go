@@ -0,0 +1,265 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type loopControl struct {
+	next  bool
+	depth int
+}
+
+func (e *loopControl) Error() string {
+	if e.next {
+		return "continue"
+	}
+	return "break"
+}
+
+type functionReturn struct {
+	code int
+}
+
+func (e *functionReturn) Error() string {
+	return fmt.Sprintf("return %d", e.code)
+}
+
+func isControlFlow(err error) bool {
+	var exit *shellExit
+	var loop *loopControl
+	var ret *functionReturn
+	return errors.As(err, &exit) || errors.As(err, &loop) || errors.As(err, &ret)
+}
+
+func (sh *shell) withRedirects(redirs []*redirection, std *stdio, fn func(*stdio) error) error {
+	std, closeFiles, err := sh.applyRedirection(redirs, std)
+	if err != nil {
+		return err
+	}
+	defer closeFiles()
+
+	return fn(std)
+}
+
+func (sh *shell) executeCondition(list *commandList, std *stdio) (bool, error) {
+	sh.conditionDepth++
+	err := sh.executeList(list, std)
+	sh.conditionDepth--
+
+	if isControlFlow(err) {
+		return false, err
+	}
+	return err == nil, nil
+}
+
+func (sh *shell) executeBraceGroup(c *braceGroup, std *stdio) error {
+	return sh.withRedirects(c.redirs, std, func(std *stdio) error {
+		return sh.executeList(c.body, std)
+	})
+}
+
+func (sh *shell) executeIf(c *ifClause, std *stdio) error {
+	return sh.withRedirects(c.redirs, std, func(std *stdio) error {
+		for _, branch := range c.branches {
+			ok, err := sh.executeCondition(branch.condition, std)
+			if err != nil {
+				return err
+			}
+			if ok {
+				return sh.executeList(branch.body, std)
+			}
+		}
+
+		if c.elseBody != nil {
+			return sh.executeList(c.elseBody, std)
+		}
+		return nil
+	})
+}
+
+func (sh *shell) executeLoop(c *loopClause, std *stdio) error {
+	return sh.withRedirects(c.redirs, std, func(std *stdio) error {
+		sh.loopDepth++
+		defer func() {
+			sh.loopDepth--
+		}()
+
+		var status error
+		for {
+			ok, err := sh.executeCondition(c.condition, std)
+			if done, err := sh.loopResult(err); done {
+				return err
+			}
+			if ok == c.until {
+				return status
+			}
+
+			err = sh.executeList(c.body, std)
+			done, err := sh.loopResult(err)
+			if done {
+				return err
+			}
+			status = err
+		}
+	})
+}
+
+func (sh *shell) executeFor(c *forClause, std *stdio) error {
+	return sh.withRedirects(c.redirs, std, func(std *stdio) error {
+		items := append([]string(nil), sh.args...)
+		if c.words != nil {
+			var err error
+			items, err = sh.expandWords(c.words)
+			if err != nil {
+				return err
+			}
+		}
+
+		sh.loopDepth++
+		defer func() {
+			sh.loopDepth--
+		}()
+
+		var status error
+		for _, item := range items {
+			sh.setVar(c.name, item)
+
+			err := sh.executeList(c.body, std)
+			done, err := sh.loopResult(err)
+			if done {
+				return err
+			}
+			status = err
+		}
+		return status
+	})
+}
+
+func (sh *shell) loopResult(err error) (bool, error) {
+	var loop *loopControl
+	if !errors.As(err, &loop) {
+		return isControlFlow(err), err
+	}
+	if loop.depth > 1 {
+		return true, &loopControl{next: loop.next, depth: loop.depth - 1}
+	}
+	return !loop.next, nil
+}
+
+func (sh *shell) executeCase(c *caseClause, std *stdio) error {
+	return sh.withRedirects(c.redirs, std, func(std *stdio) error {
+		subject, err := sh.expandString(&word{parts: sh.expandTilde(c.subject.parts, false)})
+		if err != nil {
+			return err
+		}
+
+		for _, item := range c.items {
+			for _, pattern := range item.patterns {
+				expanded, err := sh.expandPattern(&word{parts: sh.expandTilde(pattern.parts, false)})
+				if err != nil {
+					return err
+				}
+				if matchPattern(expanded, subject) {
+					return sh.executeList(item.body, std)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (sh *shell) defineFunction(c *functionDef) error {
+	if sh.functions == nil {
+		sh.functions = make(map[string]*functionDef)
+	}
+	sh.functions[c.name] = c
+	return nil
+}
+
+func (sh *shell) callFunction(fn *functionDef, args []string, std *stdio) error {
+	savedArgs := sh.args
+	sh.args = args[1:]
+	sh.locals = append(sh.locals, make(map[string]*variable))
+
+	defer func() {
+		frame := sh.locals[len(sh.locals)-1]
+		sh.locals = sh.locals[:len(sh.locals)-1]
+		for name, v := range frame {
+			if v == nil {
+				delete(sh.vars, name)
+			} else {
+				sh.vars[name] = v
+			}
+		}
+		sh.args = savedArgs
+	}()
+
+	err := sh.executeCommand(fn.body, std)
+
+	var ret *functionReturn
+	if errors.As(err, &ret) {
+		return statusFromCode(ret.code)
+	}
+	return err
+}
+
+func (sh *shell) local(args []string) error {
+	if len(sh.locals) == 0 {
+		return errors.New("local: can only be used in a function")
+	}
+	frame := sh.locals[len(sh.locals)-1]
+
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !isName(name) {
+			return fmt.Errorf("local: `%s': not a valid identifier", arg)
+		}
+
+		if _, saved := frame[name]; !saved {
+			frame[name] = sh.vars[name]
+		}
+		delete(sh.vars, name)
+		if hasValue {
+			sh.setVar(name, value)
+		}
+	}
+
+	return nil
+}
+
+func (sh *shell) returnFromFunction(args []string) error {
+	if len(sh.locals) == 0 && sh.sourceDepth == 0 {
+		return errors.New("return: can only `return' from a function or sourced script")
+	}
+
+	code := sh.status
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("return: %s: numeric argument required", args[0])
+		}
+		code = n & 0xff
+	}
+	return &functionReturn{code: code}
+}
+
+func (sh *shell) loopControl(name string, args []string, std *stdio) error {
+	n := 1
+	if len(args) > 0 {
+		var err error
+		n, err = strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return fmt.Errorf("%s: %s: loop count out of range", name, args[0])
+		}
+	}
+
+	if sh.loopDepth == 0 {
+		fmt.Fprintf(std.err(), "%s: only meaningful in a `for', `while', or `until' loop\n", name)
+		return nil
+	}
+
+	return &loopControl{next: name == "continue", depth: min(n, sh.loopDepth)}
+}
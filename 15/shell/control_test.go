@@ -0,0 +1,136 @@
+package shell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCompound(t *testing.T) {
+	tests := []struct {
+		input string
+		want  command
+	}{
+		{"if true; then echo a; fi", &ifClause{}},
+		{"while false\ndo\n  echo a\ndone < in.txt", &loopClause{}},
+		{"until true; do :; done", &loopClause{}},
+		{"for x in a b; do echo $x; done", &forClause{}},
+		{"for x\ndo echo $x; done", &forClause{}},
+		{"case $x in a|b) echo ab;; *) ;; esac", &caseClause{}},
+		{"{ echo a; echo b; } > out.txt", &braceGroup{}},
+		{"f() { echo a; }", &functionDef{}},
+		{"function f { echo a; }", &functionDef{}},
+	}
+
+	for _, test := range tests {
+		list, err := parse(test.input)
+		assert.NoError(t, err, test.input)
+		assert.IsType(t, test.want, list.items[0].commands[0].pipeline.commands[0], test.input)
+	}
+
+	list, err := parse("if a; then b; elif c; then d; else e; fi 2> err.log")
+	assert.NoError(t, err)
+	c := list.items[0].commands[0].pipeline.commands[0].(*ifClause)
+	assert.Len(t, c.branches, 2)
+	assert.NotNil(t, c.elseBody)
+	assert.Len(t, c.redirs, 1)
+
+	list, err = parse("echo if then fi done")
+	assert.NoError(t, err)
+	assert.Len(t, list.items[0].commands[0].pipeline.commands[0].(*simpleCommand).args, 5)
+}
+
+func TestParseCompoundErrors(t *testing.T) {
+	tests := []string{
+		"if true; fi",
+		"if; then :; fi",
+		"while true; done",
+		"for 1x in a; do :; done",
+		"case a in a) :; esac b",
+		"fi",
+		"done",
+		"f() echo a",
+	}
+
+	for _, test := range tests {
+		_, err := parse(test)
+		assert.Error(t, err, test)
+	}
+
+	for _, test := range []string{"if true; then", "while true; do echo", "case a in", "f() {"} {
+		_, err := parse(test)
+		assert.IsType(t, &incompleteError{}, err, test)
+	}
+}
+
+func TestControlFlow(t *testing.T) {
+	tests := []struct {
+		script string
+		out    string
+		code   int
+	}{
+		{"if true; then echo yes; else echo no; fi", "yes\n", 0},
+		{"if false; then echo a; elif true; then echo b; fi", "b\n", 0},
+		{"if false; then echo a; fi", "", 0},
+		{"if true; then false; fi", "", 1},
+		{"for x in a b c; do echo $x; done", "a\nb\nc\n", 0},
+		{"for x in a{1,2} \"b c\"; do echo \"[$x]\"; done", "[a1]\n[a2]\n[b c]\n", 0},
+		{"set -- p q\nfor x; do echo $x; done", "p\nq\n", 0},
+		{"for x in 1 2 3; do [ $x = 2 ] && continue; echo $x; done", "1\n3\n", 0},
+		{"for x in 1 2 3; do for y in a b; do [ $x = 2 ] && break 2; echo $x$y; done; done", "1a\n1b\n", 0},
+		{"x=\nwhile [ \"$x\" != aaa ]; do x=${x}a; echo $x; done", "a\naa\naaa\n", 0},
+		{"x=\nuntil [ \"$x\" = aa ]; do x=${x}a; done; echo $x", "aa\n", 0},
+		{"while true; do echo once; break; done", "once\n", 0},
+		{"case main.go in *.txt) echo text;; *.go|*.c) echo source;; esac", "source\n", 0},
+		{"v='*'\ncase x in \"$v\") echo literal;; $v) echo pattern;; esac", "pattern\n", 0},
+		{"case none in a) echo a;; esac", "", 0},
+		{"{ echo a; echo b; }", "a\nb\n", 0},
+		{"f() { echo \"$# $1\"; return 4; }\nf x y; echo $?", "2 x\n4\n", 0},
+		{"f() { local v=inner; g; }\ng() { echo $v; }\nv=outer\nf; echo $v", "inner\nouter\n", 0},
+		{"f() { local v; v=set; }\nf; echo \"[${v-unset}]\"", "[unset]\n", 0},
+		{"f() { for x in 1 2; do return $x; done; }\nf; echo $?", "1\n", 0},
+		{"f() { echo $1; shift; [ $# -gt 0 ] && f \"$@\"; }\nf a b c", "a\nb\nc\n", 1},
+		{"set -e\nif false; then :; fi\nwhile false; do :; done\necho alive\nfalse\necho dead", "alive\n", 1},
+		{"return 1", "", 1},
+		{"break; echo after", "after\n", 0},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		sh := newTestShell(&out, &errOut)
+
+		err := sh.runInput(lineReader(strings.NewReader(test.script)), false)
+		assert.Equal(t, test.code, exitCode(err), test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+	}
+}
+
+func TestUnset(t *testing.T) {
+	tests := []struct {
+		script string
+		out    string
+		errOut string
+		code   int
+	}{
+		{"f() { echo f; }\nunset -f f; f", "", "f: command not found\n", 127},
+		{"f() { echo f; }\nf=1; unset f; echo ${f-none}; f", "none\nf\n", "", 0},
+		{"f() { echo f; }\nunset f; f", "", "f: command not found\n", 127},
+		{"f() { echo f; }\nf=1; unset -v f; unset -v f; echo ${f-none}; f", "none\nf\n", "", 0},
+		{"a=1 b=2; unset -- a b; echo ${a-x}${b-y}", "xy\n", "", 0},
+		{"unset -fv f", "", "unset: cannot simultaneously unset a function and a variable\n", 1},
+		{"unset -x f", "", "unset: -x: invalid option\n", 1},
+		{"unset 1a", "", "unset: `1a': not a valid identifier\n", 1},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		sh := newTestShell(&out, &errOut)
+
+		err := sh.runInput(lineReader(strings.NewReader(test.script)), false)
+		assert.Equal(t, test.code, exitCode(err), test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Equal(t, test.errOut, errOut.String(), test.script)
+	}
+}
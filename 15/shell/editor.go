@@ -0,0 +1,491 @@
+package shell
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+const (
+	keyUp rune = -(iota + 1)
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+const (
+	keyCtrlA     = 0x01
+	keyCtrlB     = 0x02
+	keyCtrlC     = 0x03
+	keyCtrlD     = 0x04
+	keyCtrlE     = 0x05
+	keyCtrlF     = 0x06
+	keyCtrlG     = 0x07
+	keyCtrlH     = 0x08
+	keyTab       = 0x09
+	keyCtrlK     = 0x0b
+	keyCtrlL     = 0x0c
+	keyEnter     = 0x0d
+	keyNewline   = 0x0a
+	keyCtrlN     = 0x0e
+	keyCtrlP     = 0x10
+	keyCtrlR     = 0x12
+	keyCtrlU     = 0x15
+	keyCtrlW     = 0x17
+	keyEscape    = 0x1b
+	keyBackspace = 0x7f
+)
+
+var errInterrupted = errors.New("interrupted")
+
+type completer func(line []rune, pos int) (int, []string)
+
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	fd       int
+	history  *history
+	complete completer
+
+	prompt  string
+	width   int
+	line    []rune
+	pos     int
+	histPos int
+	saved   []rune
+	lastTab bool
+}
+
+func newLineEditor(in io.Reader, out io.Writer, fd int, hist *history, complete completer) *lineEditor {
+	return &lineEditor{
+		in:       bufio.NewReader(in),
+		out:      out,
+		fd:       fd,
+		history:  hist,
+		complete: complete,
+	}
+}
+
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	if term.IsTerminal(e.fd) {
+		state, err := term.MakeRaw(e.fd)
+		if err != nil {
+			return "", err
+		}
+		defer func() {
+			_ = term.Restore(e.fd, state)
+		}()
+	}
+
+	e.prompt, e.width = visiblePrompt(prompt)
+	e.line = nil
+	e.pos = 0
+	e.histPos = len(e.history.entries)
+	e.saved = nil
+	e.lastTab = false
+
+	e.write(e.prompt)
+
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			if len(e.line) > 0 && errors.Is(err, io.EOF) {
+				e.write("\r\n")
+				return string(e.line), nil
+			}
+			return "", err
+		}
+
+		tab := false
+
+		switch key {
+		case keyEnter, keyNewline:
+			e.write("\r\n")
+			return string(e.line), nil
+		case keyCtrlC:
+			e.write("^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				e.write("\r\n")
+				return "", io.EOF
+			}
+			e.deleteChar()
+		case keyBackspace, keyCtrlH:
+			if e.pos > 0 {
+				e.pos--
+				e.deleteChar()
+			}
+		case keyDelete:
+			e.deleteChar()
+		case keyLeft, keyCtrlB:
+			e.pos = max(e.pos-1, 0)
+		case keyRight, keyCtrlF:
+			e.pos = min(e.pos+1, len(e.line))
+		case keyHome, keyCtrlA:
+			e.pos = 0
+		case keyEnd, keyCtrlE:
+			e.pos = len(e.line)
+		case keyCtrlK:
+			e.line = e.line[:e.pos]
+		case keyCtrlU:
+			e.line = append([]rune(nil), e.line[e.pos:]...)
+			e.pos = 0
+		case keyCtrlW:
+			e.deleteWord()
+		case keyCtrlL:
+			e.write("\x1b[H\x1b[2J")
+			e.write(e.prompt)
+		case keyUp, keyCtrlP:
+			e.historyMove(-1)
+		case keyDown, keyCtrlN:
+			e.historyMove(1)
+		case keyCtrlR:
+			accepted, err := e.reverseSearch()
+			if err != nil {
+				return "", err
+			}
+			if accepted {
+				e.refresh()
+				e.write("\r\n")
+				return string(e.line), nil
+			}
+		case keyTab:
+			tab = true
+			e.completeWord()
+		default:
+			if key >= 0 && unicode.IsPrint(key) {
+				e.insert(key)
+			}
+		}
+
+		e.lastTab = tab
+		e.refresh()
+	}
+}
+
+func (e *lineEditor) write(s string) {
+	_, _ = io.WriteString(e.out, s)
+}
+
+func (e *lineEditor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if r != keyEscape {
+		return r, nil
+	}
+
+	next, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if next != '[' && next != 'O' {
+		return keyUnknown, nil
+	}
+
+	code, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+
+	switch code {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	}
+
+	if code < '0' || code > '9' {
+		return keyUnknown, nil
+	}
+
+	seq := string(code)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		if r == '~' {
+			break
+		}
+		if (r < '0' || r > '9') && r != ';' {
+			return keyUnknown, nil
+		}
+		seq += string(r)
+	}
+
+	switch seq {
+	case "1", "7":
+		return keyHome, nil
+	case "4", "8":
+		return keyEnd, nil
+	case "3":
+		return keyDelete, nil
+	}
+	return keyUnknown, nil
+}
+
+func (e *lineEditor) promptLine() string {
+	if i := strings.LastIndexByte(e.prompt, '\n'); i >= 0 {
+		return e.prompt[i+1:]
+	}
+	return e.prompt
+}
+
+func (e *lineEditor) refresh() {
+	var sb strings.Builder
+	sb.WriteString("\r")
+	sb.WriteString(e.promptLine())
+	sb.WriteString(string(e.line))
+	sb.WriteString("\x1b[K\r")
+	if n := e.width + e.pos; n > 0 {
+		fmt.Fprintf(&sb, "\x1b[%dC", n)
+	}
+	e.write(sb.String())
+}
+
+func (e *lineEditor) insert(runes ...rune) {
+	line := make([]rune, 0, len(e.line)+len(runes))
+	line = append(line, e.line[:e.pos]...)
+	line = append(line, runes...)
+	line = append(line, e.line[e.pos:]...)
+	e.line = line
+	e.pos += len(runes)
+}
+
+func (e *lineEditor) deleteChar() {
+	if e.pos < len(e.line) {
+		e.line = append(e.line[:e.pos], e.line[e.pos+1:]...)
+	}
+}
+
+func (e *lineEditor) deleteWord() {
+	start := e.pos
+	for start > 0 && e.line[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && e.line[start-1] != ' ' {
+		start--
+	}
+	e.line = append(e.line[:start], e.line[e.pos:]...)
+	e.pos = start
+}
+
+func (e *lineEditor) historyMove(delta int) {
+	next := e.histPos + delta
+	if next < 0 || next > len(e.history.entries) {
+		return
+	}
+
+	if e.histPos == len(e.history.entries) {
+		e.saved = append([]rune(nil), e.line...)
+	}
+	e.histPos = next
+
+	if next == len(e.history.entries) {
+		e.line = append([]rune(nil), e.saved...)
+	} else {
+		e.line = []rune(e.history.entries[next])
+	}
+	e.pos = len(e.line)
+}
+
+func (e *lineEditor) reverseSearch() (bool, error) {
+	original := append([]rune(nil), e.line...)
+	query := ""
+	match := -1
+	failed := false
+
+	render := func() {
+		label := "reverse-i-search"
+		if failed {
+			label = "failed " + label
+		}
+		e.write(fmt.Sprintf("\r(%s)`%s': %s\x1b[K", label, query, string(e.line)))
+	}
+
+	find := func(from int) {
+		if query == "" {
+			return
+		}
+		i := e.history.search(query, from)
+		failed = i < 0
+		if i >= 0 {
+			match = i
+			e.line = []rune(e.history.entries[i])
+			e.pos = len(e.line)
+		}
+	}
+
+	render()
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			return false, err
+		}
+
+		switch key {
+		case keyCtrlR:
+			if match > 0 {
+				find(match - 1)
+			} else if match < 0 {
+				find(len(e.history.entries) - 1)
+			}
+		case keyBackspace, keyCtrlH:
+			if query != "" {
+				query = string([]rune(query)[:len([]rune(query))-1])
+				find(len(e.history.entries) - 1)
+			}
+		case keyCtrlG, keyCtrlC:
+			e.line = original
+			e.pos = len(e.line)
+			return false, nil
+		case keyEnter, keyNewline:
+			if match >= 0 {
+				e.histPos = match
+			}
+			return true, nil
+		default:
+			if key >= 0 && unicode.IsPrint(key) {
+				query += string(key)
+				from := len(e.history.entries) - 1
+				if match >= 0 {
+					from = match
+				}
+				find(from)
+				break
+			}
+			if match >= 0 {
+				e.histPos = match
+			}
+			return false, nil
+		}
+
+		render()
+	}
+}
+
+func (e *lineEditor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+
+	start, candidates := e.complete(e.line, e.pos)
+	if len(candidates) == 0 {
+		return
+	}
+
+	word := unescapeWord(string(e.line[start:e.pos]))
+
+	if len(candidates) == 1 {
+		completion := escapeWord(candidates[0])
+		if !strings.HasSuffix(completion, "/") {
+			completion += " "
+		}
+		e.replace(start, completion)
+		return
+	}
+
+	prefix := commonPrefix(candidates)
+	if len([]rune(prefix)) > len([]rune(word)) {
+		e.replace(start, escapeWord(prefix))
+		return
+	}
+
+	if e.lastTab {
+		e.write("\r\n")
+		e.write(formatColumns(candidates, 80))
+		e.write(e.prompt)
+	}
+}
+
+func (e *lineEditor) replace(start int, text string) {
+	rest := append([]rune(nil), e.line[e.pos:]...)
+	e.line = append(e.line[:start], []rune(text)...)
+	e.pos = len(e.line)
+	e.line = append(e.line, rest...)
+}
+
+func commonPrefix(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+
+	prefix := []rune(words[0])
+	for _, w := range words[1:] {
+		runes := []rune(w)
+		n := 0
+		for n < len(prefix) && n < len(runes) && prefix[n] == runes[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return string(prefix)
+}
+
+func formatColumns(words []string, width int) string {
+	words = append([]string(nil), words...)
+	sort.Strings(words)
+
+	colWidth := 0
+	for _, w := range words {
+		colWidth = max(colWidth, len([]rune(w))+2)
+	}
+	cols := max(width/colWidth, 1)
+
+	var sb strings.Builder
+	for i, w := range words {
+		sb.WriteString(w)
+		if (i+1)%cols == 0 || i == len(words)-1 {
+			sb.WriteString("\r\n")
+		} else {
+			sb.WriteString(strings.Repeat(" ", colWidth-len([]rune(w))))
+		}
+	}
+	return sb.String()
+}
+
+func escapeWord(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(" \t'\"\\$`&|;()<>*?[]#{}", r) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func unescapeWord(s string) string {
+	var sb strings.Builder
+	escaped := false
+	for _, r := range s {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,146 @@
+package shell
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestEditor(input string, entries ...string) *lineEditor {
+	hist := &history{entries: entries, size: defaultHistorySize}
+	return newLineEditor(strings.NewReader(input), io.Discard, -1, hist, nil)
+}
+
+func TestLineEditorEditing(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"echo hi\r", "echo hi"},
+		{"echo hx\x7fi\r", "echo hi"},
+		{"echo ac\x1b[Db\r", "echo abc"},
+		{"cho\x01e\r", "echo"},
+		{"echo abc\x01\x1b[3~\x05d\r", "cho abcd"},
+		{"echo abc def\x17\r", "echo abc "},
+		{"echo abc\x1b[D\x1b[D\x0b\r", "echo a"},
+		{"echo abc\x1b[D\x1b[D\x15\r", "bc"},
+		{"abc\x02\x02\x06X\r", "abXc"},
+		{"ab\x1b[Hx\x1b[Fy\r", "xaby"},
+		{"last line without newline", "last line without newline"},
+	}
+
+	for _, test := range tests {
+		line, err := newTestEditor(test.input).readLine("$ ")
+		assert.NoError(t, err, test.input)
+		assert.Equal(t, test.want, line, test.input)
+	}
+}
+
+func TestLineEditorControl(t *testing.T) {
+	_, err := newTestEditor("\x04").readLine("$ ")
+	assert.ErrorIs(t, err, io.EOF)
+
+	_, err = newTestEditor("echo\x03").readLine("$ ")
+	assert.ErrorIs(t, err, errInterrupted)
+
+	line, err := newTestEditor("ab\x01\x04\r").readLine("$ ")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", line)
+}
+
+func TestLineEditorRefresh(t *testing.T) {
+	tests := []struct {
+		prompt string
+		input  string
+		want   string
+	}{
+		{"$ ", "ab", "\r$ ab\x1b[K\r\x1b[4C"},
+		{"$ ", "ab\x1b[D", "\r$ ab\x1b[K\r\x1b[3C"},
+		{"\x01\x1b[1m\x02$\x01\x1b[0m\x02 ", "ab\x01", "\r\x1b[1m$\x1b[0m ab\x1b[K\r\x1b[2C"},
+		{"top\n> ", "a", "\r> a\x1b[K\r\x1b[3C"},
+		{"", "a\x01", "\ra\x1b[K\r"},
+	}
+
+	for _, test := range tests {
+		var out strings.Builder
+		editor := newLineEditor(strings.NewReader(test.input+"\r"), &out, -1, &history{}, nil)
+		_, err := editor.readLine(test.prompt)
+		assert.NoError(t, err, test.input)
+		assert.True(t, strings.HasSuffix(out.String(), test.want+"\r\n"), "%q: %q", test.input, out.String())
+	}
+}
+
+func TestLineEditorHistory(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"\x1b[A\r", "echo three"},
+		{"\x1b[A\x1b[A\r", "ls two"},
+		{"\x1b[A\x1b[A\x1b[A\x1b[A\x1b[A\r", "echo one"},
+		{"new\x1b[A\x1b[B\r", "new"},
+		{"\x10\x10\x0e\r", "echo three"},
+		{"\x12two\r", "ls two"},
+		{"\x12echo\x12\r", "echo one"},
+		{"\x12echo\x12\x12\r", "echo one"},
+		{"keep\x12ls\x07\r", "keep"},
+		{"\x12ls\x1b[Cx\r", "ls twox"},
+	}
+
+	for _, test := range tests {
+		editor := newTestEditor(test.input, "echo one", "ls two", "echo three")
+		line, err := editor.readLine("$ ")
+		assert.NoError(t, err, test.input)
+		assert.Equal(t, test.want, line, test.input)
+	}
+}
+
+func TestLineEditorCompletion(t *testing.T) {
+	complete := func(line []rune, pos int) (int, []string) {
+		start := strings.LastIndex(string(line[:pos]), " ") + 1
+		var res []string
+		for _, c := range []string{"main.go", "main_test.go", "my file.txt", "docs/"} {
+			if strings.HasPrefix(c, string(line[start:pos])) {
+				res = append(res, c)
+			}
+		}
+		return start, res
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"cat d\t\r", "cat docs/"},
+		{"cat ma\t\r", "cat main"},
+		{"cat main.\t\r", "cat main.go "},
+		{"cat my\t\r", `cat my\ file.txt `},
+		{"cat x\t\r", "cat x"},
+	}
+
+	for _, test := range tests {
+		editor := newTestEditor(test.input)
+		editor.complete = complete
+		line, err := editor.readLine("$ ")
+		assert.NoError(t, err, test.input)
+		assert.Equal(t, test.want, line, test.input)
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  string
+	}{
+		{[]string{"main.go", "main_test.go"}, "main"},
+		{[]string{"abc"}, "abc"},
+		{[]string{"abc", "xyz"}, ""},
+		{nil, ""},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, commonPrefix(test.words))
+	}
+}
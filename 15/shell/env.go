@@ -0,0 +1,124 @@
+package shell
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+type variable struct {
+	value    string
+	exported bool
+}
+
+func environVars(environ []string) map[string]*variable {
+	vars := make(map[string]*variable)
+	for _, entry := range environ {
+		name, value, ok := strings.Cut(entry, "=")
+		if ok && isName(name) {
+			vars[name] = &variable{value: value, exported: true}
+		}
+	}
+	return vars
+}
+
+func cloneVars(vars map[string]*variable) map[string]*variable {
+	res := make(map[string]*variable, len(vars))
+	for name, v := range vars {
+		copied := *v
+		res[name] = &copied
+	}
+	return res
+}
+
+func (sh *shell) lookupVar(name string) (string, bool) {
+	v, ok := sh.vars[name]
+	if !ok {
+		return "", false
+	}
+	return v.value, true
+}
+
+func (sh *shell) getVar(name string) string {
+	value, _ := sh.lookupVar(name)
+	return value
+}
+
+func (sh *shell) setVar(name, value string) {
+	if v, ok := sh.vars[name]; ok {
+		v.value = value
+		return
+	}
+	sh.vars[name] = &variable{value: value}
+}
+
+func (sh *shell) exportVar(name string) {
+	v, ok := sh.vars[name]
+	if !ok {
+		v = &variable{}
+		sh.vars[name] = v
+	}
+	v.exported = true
+}
+
+func (sh *shell) unsetVar(name string) {
+	delete(sh.vars, name)
+}
+
+func (sh *shell) environ() []string {
+	env := make([]string, 0, len(sh.vars))
+	for name, v := range sh.vars {
+		if v.exported {
+			env = append(env, name+"="+v.value)
+		}
+	}
+	sort.Strings(env)
+	return env
+}
+
+func (sh *shell) commandEnv(assigns map[string]string) []string {
+	if len(assigns) == 0 {
+		return sh.environ()
+	}
+
+	env := make([]string, 0, len(sh.vars)+len(assigns))
+	for _, entry := range sh.environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		if _, ok := assigns[name]; !ok {
+			env = append(env, entry)
+		}
+	}
+	for name, value := range assigns {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+func (sh *shell) withTemporaryVars(assigns map[string]string, fn func() error) error {
+	saved := make(map[string]*variable, len(assigns))
+	for name, value := range assigns {
+		saved[name] = sh.vars[name]
+		sh.vars[name] = &variable{value: value, exported: true}
+	}
+
+	defer func() {
+		for name, v := range saved {
+			if v == nil {
+				delete(sh.vars, name)
+			} else {
+				sh.vars[name] = v
+			}
+		}
+	}()
+
+	return fn()
+}
+
+func (sh *shell) homeDir() string {
+	if home := sh.getVar("HOME"); home != "" {
+		return home
+	}
+	home, _ := os.UserHomeDir()
+	return home
+}
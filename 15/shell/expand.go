@@ -0,0 +1,434 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type expansionError struct {
+	message string
+}
+
+func (e *expansionError) Error() string {
+	return e.message
+}
+
+type field struct {
+	text    string
+	pattern string
+	glob    bool
+}
+
+type fieldBuilder struct {
+	fields  []field
+	cur     strings.Builder
+	pattern strings.Builder
+	glob    bool
+	have    bool
+}
+
+func (f *fieldBuilder) write(s string, quoted bool) {
+	f.cur.WriteString(s)
+	if quoted {
+		f.pattern.WriteString(escapePattern(s))
+	} else {
+		f.pattern.WriteString(strings.ReplaceAll(s, `\`, `\\`))
+		f.glob = f.glob || strings.ContainsAny(s, "*?[")
+	}
+	f.have = true
+}
+
+func (f *fieldBuilder) split(s string, ifs string) {
+	for _, r := range s {
+		if strings.ContainsRune(ifs, r) {
+			f.end()
+			continue
+		}
+		f.write(string(r), false)
+	}
+}
+
+func (f *fieldBuilder) end() {
+	if f.have {
+		f.fields = append(f.fields, field{text: f.cur.String(), pattern: f.pattern.String(), glob: f.glob})
+	}
+	f.cur.Reset()
+	f.pattern.Reset()
+	f.glob = false
+	f.have = false
+}
+
+func (sh *shell) expandWords(words []*word) ([]string, error) {
+	args := make([]string, 0, len(words))
+	for _, w := range words {
+		for _, expanded := range expandBraces(w) {
+			fields, err := sh.expandWord(expanded)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, fields...)
+		}
+	}
+	return args, nil
+}
+
+func (sh *shell) ifs() string {
+	if ifs, ok := sh.lookupVar("IFS"); ok {
+		return ifs
+	}
+	return " \t\n"
+}
+
+func (sh *shell) expandWord(w *word) ([]string, error) {
+	f := &fieldBuilder{}
+
+	for _, part := range sh.expandTilde(w.parts, false) {
+		if !part.expands() {
+			if part.quoted || part.text != "" {
+				f.write(part.text, part.quoted)
+			}
+			continue
+		}
+
+		if part.param != nil && part.param.op == "" && (part.param.name == "@" || part.param.name == "*") {
+			sh.expandPositional(f, part.param.name, part.quoted)
+			continue
+		}
+
+		value, err := sh.partValue(part)
+		if err != nil {
+			return nil, err
+		}
+
+		if part.quoted {
+			f.write(value, true)
+		} else {
+			f.split(value, sh.ifs())
+		}
+	}
+	f.end()
+
+	return sh.expandFields(f.fields)
+}
+
+func (sh *shell) expandFields(fields []field) ([]string, error) {
+	res := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !field.glob || sh.options["noglob"] {
+			res = append(res, field.text)
+			continue
+		}
+
+		matches := glob(sh.dir, field.pattern)
+		switch {
+		case len(matches) > 0:
+			res = append(res, matches...)
+		case sh.options["failglob"]:
+			return nil, fmt.Errorf("no match: %s", field.text)
+		case !sh.options["nullglob"]:
+			res = append(res, field.text)
+		}
+	}
+	return res, nil
+}
+
+func (sh *shell) expandPositional(f *fieldBuilder, name string, quoted bool) {
+	if !quoted {
+		for i, arg := range sh.args {
+			if i > 0 {
+				f.end()
+			}
+			f.split(arg, sh.ifs())
+		}
+		return
+	}
+
+	if name == "*" {
+		sep := ""
+		if ifs := sh.ifs(); ifs != "" {
+			sep = ifs[:1]
+		}
+		f.write(strings.Join(sh.args, sep), true)
+		return
+	}
+
+	for i, arg := range sh.args {
+		if i > 0 {
+			f.end()
+		}
+		f.write(arg, true)
+	}
+}
+
+func (sh *shell) expandString(w *word) (string, error) {
+	var sb strings.Builder
+	for _, part := range w.parts {
+		value, err := sh.partValue(part)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(value)
+	}
+	return sb.String(), nil
+}
+
+func (sh *shell) partValue(part wordPart) (string, error) {
+	switch {
+	case part.command != nil:
+		return sh.commandSubst(part.command)
+	case part.param != nil:
+		return sh.expandParam(part.param)
+	}
+	return part.text, nil
+}
+
+func (sh *shell) commandSubst(list *commandList) (string, error) {
+	var out bytes.Buffer
+
+	sub := sh.subshell()
+	sub.interactive = false
+	sub.job = nil
+	sub.jobs = &jobTable{}
+
+	std := sh.std.clone()
+	std.set(1, lockWriter(&out))
+	sub.std = std
+	err := sub.runExitTrap(sub.executeList(list, std))
+	sh.substStatus = exitCode(err)
+	sh.status = sh.substStatus
+	if canceled := sh.canceled(); canceled != nil {
+		return "", canceled
+	}
+
+	return strings.TrimRight(out.String(), "\n"), nil
+}
+
+func (sh *shell) expandPattern(w *word) (string, error) {
+	var sb strings.Builder
+	for _, part := range w.parts {
+		text, err := sh.partValue(part)
+		if err != nil {
+			return "", err
+		}
+		if part.quoted {
+			text = escapePattern(text)
+		}
+		sb.WriteString(text)
+	}
+	return sb.String(), nil
+}
+
+func (sh *shell) specialParam(name string) (string, bool) {
+	switch name {
+	case "?":
+		return strconv.Itoa(sh.status), true
+	case "$":
+		return strconv.Itoa(os.Getpid()), true
+	case "!":
+		if sh.lastBgPid == 0 {
+			return "", false
+		}
+		return strconv.Itoa(sh.lastBgPid), true
+	case "#":
+		return strconv.Itoa(len(sh.args)), true
+	case "0":
+		return sh.name, true
+	case "@", "*":
+		return strings.Join(sh.args, " "), len(sh.args) > 0
+	case "-":
+		return sh.optionFlags(), true
+	}
+
+	if isNumber(name) {
+		n, err := strconv.Atoi(name)
+		if err != nil || n < 1 || n > len(sh.args) {
+			return "", false
+		}
+		return sh.args[n-1], true
+	}
+
+	return "", false
+}
+
+func (sh *shell) paramValue(name string) (string, bool) {
+	if base, index, ok := splitSubscript(name); ok {
+		return sh.elementValue(base, index)
+	}
+	if isName(name) {
+		return sh.lookupVar(name)
+	}
+	return sh.specialParam(name)
+}
+
+// elementValue indexes a variable holding a space-separated list, such as
+// PIPESTATUS.
+func (sh *shell) elementValue(name, index string) (string, bool) {
+	value, set := sh.lookupVar(name)
+	if index == "@" || index == "*" {
+		return value, set
+	}
+
+	n, err := strconv.Atoi(index)
+	fields := strings.Fields(value)
+	if err != nil || n < 0 || n >= len(fields) {
+		return "", false
+	}
+	return fields[n], true
+}
+
+func (sh *shell) expandParam(p *paramExp) (string, error) {
+	value, set := sh.paramValue(p.name)
+
+	switch p.op {
+	case "", "len":
+		if !set && sh.options["nounset"] && p.name != "@" && p.name != "*" {
+			return "", &expansionError{message: p.name + ": unbound variable"}
+		}
+		if _, index, ok := splitSubscript(p.name); ok && p.op == "len" && (index == "@" || index == "*") {
+			return strconv.Itoa(len(strings.Fields(value))), nil
+		}
+		if p.op == "len" {
+			return strconv.Itoa(utf8.RuneCountInString(value)), nil
+		}
+		return value, nil
+	case ":-", "-":
+		if !set || (p.op == ":-" && value == "") {
+			return sh.expandString(p.arg)
+		}
+		return value, nil
+	case ":=", "=":
+		if !set || (p.op == ":=" && value == "") {
+			if !isName(p.name) {
+				return "", fmt.Errorf("$%s: cannot assign in this way", p.name)
+			}
+			arg, err := sh.expandString(p.arg)
+			if err != nil {
+				return "", err
+			}
+			sh.setVar(p.name, arg)
+			return arg, nil
+		}
+		return value, nil
+	case ":+", "+":
+		if !set || (p.op == ":+" && value == "") {
+			return "", nil
+		}
+		return sh.expandString(p.arg)
+	case ":?", "?":
+		if !set || (p.op == ":?" && value == "") {
+			message, err := sh.expandString(p.arg)
+			if err != nil {
+				return "", err
+			}
+			if message == "" {
+				message = "parameter null or not set"
+			}
+			return "", &expansionError{message: p.name + ": " + message}
+		}
+		return value, nil
+	case "#", "##", "%", "%%":
+		pattern, err := sh.expandPattern(p.arg)
+		if err != nil {
+			return "", err
+		}
+		return removePattern(value, pattern, p.op), nil
+	}
+
+	return "", fmt.Errorf("%s: bad substitution", p.name)
+}
+
+func removePattern(value, pattern, op string) string {
+	runes := []rune(value)
+
+	switch op {
+	case "#":
+		for i := 0; i <= len(runes); i++ {
+			if matchPattern(pattern, string(runes[:i])) {
+				return string(runes[i:])
+			}
+		}
+	case "##":
+		for i := len(runes); i >= 0; i-- {
+			if matchPattern(pattern, string(runes[:i])) {
+				return string(runes[i:])
+			}
+		}
+	case "%":
+		for i := len(runes); i >= 0; i-- {
+			if matchPattern(pattern, string(runes[i:])) {
+				return string(runes[:i])
+			}
+		}
+	case "%%":
+		for i := 0; i <= len(runes); i++ {
+			if matchPattern(pattern, string(runes[i:])) {
+				return string(runes[:i])
+			}
+		}
+	}
+
+	return value
+}
+
+func (sh *shell) expandTilde(parts []wordPart, assignment bool) []wordPart {
+	res := make([]wordPart, 0, len(parts))
+	start := true
+
+	for i, part := range parts {
+		if part.quoted || part.expands() {
+			res = append(res, part)
+			start = false
+			continue
+		}
+
+		var sb strings.Builder
+		text := part.text
+		for text != "" {
+			if start && text[0] == '~' {
+				end := strings.IndexByte(text, '/')
+				if assignment {
+					end = strings.IndexAny(text, "/:")
+				}
+				if end < 0 {
+					end = len(text)
+				}
+				if dir, ok := sh.tildeDir(text[1:end]); ok && (end < len(text) || i == len(parts)-1) {
+					if sb.Len() > 0 {
+						res = append(res, wordPart{text: sb.String()})
+						sb.Reset()
+					}
+					res = append(res, wordPart{text: dir, quoted: true})
+					text = text[end:]
+					start = false
+					continue
+				}
+			}
+			start = assignment && text[0] == ':'
+			sb.WriteByte(text[0])
+			text = text[1:]
+		}
+		if sb.Len() > 0 {
+			res = append(res, wordPart{text: sb.String()})
+		}
+	}
+
+	return res
+}
+
+func (sh *shell) tildeDir(name string) (string, bool) {
+	if name == "" {
+		home := sh.homeDir()
+		return home, home != ""
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", false
+	}
+	return u.HomeDir, true
+}
@@ -0,0 +1,256 @@
+package shell
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func expandInput(t *testing.T, sh *shell, input string) ([]string, error) {
+	t.Helper()
+	list, err := parse(input)
+	assert.NoError(t, err, input)
+	cmd := list.items[0].commands[0].pipeline.commands[0].(*simpleCommand)
+	return sh.expandWords(cmd.args)
+}
+
+func TestExpandWords(t *testing.T) {
+	sh := &shell{
+		vars:   environVars([]string{"HOME=/home/alice", "P=/usr/lib/libc.so.6", "S=a  b\tc", "E="}),
+		args:   []string{"one", "two words"},
+		name:   "l2sh",
+		status: 3,
+	}
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"echo $HOME", []string{"echo", "/home/alice"}},
+		{"echo '$HOME' \"$HOME\"", []string{"echo", "$HOME", "/home/alice"}},
+		{"echo ${HOME}dir $HOME.x", []string{"echo", "/home/alicedir", "/home/alice.x"}},
+		{"echo \\$HOME", []string{"echo", "$HOME"}},
+		{"echo $S", []string{"echo", "a", "b", "c"}},
+		{"echo \"$S\"", []string{"echo", "a  b\tc"}},
+		{"echo $E $MISSING", []string{"echo"}},
+		{"echo \"$E\" ''", []string{"echo", "", ""}},
+		{"echo $? $# $0 $1", []string{"echo", "3", "2", "l2sh", "one"}},
+		{"echo $2", []string{"echo", "two", "words"}},
+		{"echo \"$@\"", []string{"echo", "one", "two words"}},
+		{"echo \"$*\"", []string{"echo", "one two words"}},
+		{"echo ${MISSING:-default value}", []string{"echo", "default", "value"}},
+		{"echo \"${E:-empty}\" \"${E-unset}\"", []string{"echo", "empty", ""}},
+		{"echo ${HOME:+set} ${MISSING+set}", []string{"echo", "set"}},
+		{"echo ${#HOME} ${#MISSING}", []string{"echo", "11", "0"}},
+		{"echo ${P#*/} ${P##*/}", []string{"echo", "usr/lib/libc.so.6", "libc.so.6"}},
+		{"echo ${P%.*} ${P%%.*}", []string{"echo", "/usr/lib/libc.so", "/usr/lib/libc"}},
+		{"echo ${P%\"*\"}", []string{"echo", "/usr/lib/libc.so.6"}},
+		{"echo ${P#/usr/[kl]ib/}", []string{"echo", "libc.so.6"}},
+	}
+
+	for _, test := range tests {
+		args, err := expandInput(t, sh, test.input)
+		assert.NoError(t, err, test.input)
+		assert.Equal(t, test.want, args, test.input)
+	}
+}
+
+func TestExpandAssign(t *testing.T) {
+	sh := &shell{vars: environVars(nil)}
+
+	args, err := expandInput(t, sh, "echo ${X:=first} ${X:=second}")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"echo", "first", "first"}, args)
+	assert.Equal(t, "first", sh.getVar("X"))
+
+	_, err = expandInput(t, sh, "echo ${Y:?is required}")
+	assert.EqualError(t, err, "Y: is required")
+
+	_, err = expandInput(t, sh, "echo ${Y?}")
+	assert.EqualError(t, err, "Y: parameter null or not set")
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"*", "", true},
+		{"*.go", "main.go", true},
+		{"*.go", "main.c", false},
+		{"?a?", "bar", true},
+		{"?a?", "ba", false},
+		{"[abc]x", "bx", true},
+		{"[!abc]x", "bx", false},
+		{"[a-z][0-9]", "q7", true},
+		{"[a-z][0-9]", "Q7", false},
+		{`\*`, "*", true},
+		{`\*`, "x", false},
+		{"[", "[", true},
+		{"a*b*c", "aXXbYYc", true},
+		{"a*b*c", "aXXbYY", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, matchPattern(test.pattern, test.s), test.pattern+" "+test.s)
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	sh := &shell{vars: environVars([]string{"X=v"})}
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"echo a{b,c}d", []string{"echo", "abd", "acd"}},
+		{"echo {a,b}{1,2}", []string{"echo", "a1", "a2", "b1", "b2"}},
+		{"echo {a,{b,c}}z", []string{"echo", "az", "bz", "cz"}},
+		{"echo x{,y}", []string{"echo", "x", "xy"}},
+		{"echo {1..4}", []string{"echo", "1", "2", "3", "4"}},
+		{"echo {3..1}", []string{"echo", "3", "2", "1"}},
+		{"echo {0..10..5}", []string{"echo", "0", "5", "10"}},
+		{"echo {08..10}", []string{"echo", "08", "09", "10"}},
+		{"echo {a..c}", []string{"echo", "a", "b", "c"}},
+		{"echo {$X,w}", []string{"echo", "v", "w"}},
+		{"echo {x} {} {a..} {a,b", []string{"echo", "{x}", "{}", "{a..}", "{a,b"}},
+		{"echo \"{a,b}\" \\{a,b}", []string{"echo", "{a,b}", "{a,b}"}},
+	}
+
+	for _, test := range tests {
+		args, err := expandInput(t, sh, test.input)
+		assert.NoError(t, err, test.input)
+		assert.Equal(t, test.want, args, test.input)
+	}
+}
+
+func TestExpandTilde(t *testing.T) {
+	sh := &shell{vars: environVars([]string{"HOME=/home/alice"})}
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"echo ~ ~/src a~", []string{"echo", "/home/alice", "/home/alice/src", "a~"}},
+		{"echo \"~\" ~\"/x\" \\~", []string{"echo", "~", "~/x", "~"}},
+		{"echo ~no-such-user/x", []string{"echo", "~no-such-user/x"}},
+	}
+
+	for _, test := range tests {
+		args, err := expandInput(t, sh, test.input)
+		assert.NoError(t, err, test.input)
+		assert.Equal(t, test.want, args, test.input)
+	}
+
+	assigns, err := sh.expandAssignments([]*assignment{{name: "P", value: mustLexWord(t, "~/bin:~/sbin:a~")}})
+	assert.NoError(t, err)
+	assert.Equal(t, "/home/alice/bin:/home/alice/sbin:a~", assigns["P"])
+}
+
+func TestExpandAssignments(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"X=1 Y=$X; echo $Y", "1\n"},
+		{"a=1; a=2 b=$a; echo $b", "2\n"},
+		{"a=1 a=${a}2; echo $a", "12\n"},
+		{"f() { echo $b; }; a=1; a=2 b=$a f; echo $a", "2\n1\n"},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		sh := newTestShell(&out, &errOut)
+		err := sh.runInput(lineReader(strings.NewReader(test.input)), false)
+		assert.NoError(t, err, test.input)
+		assert.Equal(t, test.want, out.String(), test.input)
+	}
+}
+
+func mustLexWord(t *testing.T, text string) *word {
+	t.Helper()
+	w, err := lexWord(text)
+	assert.NoError(t, err)
+	return w
+}
+
+func TestExpandGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go", "c.txt", ".hidden.go", "sub/x.go", "sub/y.txt", "[lit].go"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	t.Chdir(dir)
+
+	sh := &shell{vars: environVars([]string{"P=*.txt"})}
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"echo *.go", []string{"echo", "[lit].go", "a.go", "b.go"}},
+		{"echo ?.go", []string{"echo", "a.go", "b.go"}},
+		{"echo [ab].go", []string{"echo", "a.go", "b.go"}},
+		{"echo .*.go", []string{"echo", ".hidden.go"}},
+		{"echo */*.go", []string{"echo", "sub/x.go"}},
+		{"echo s*/", []string{"echo", "sub/"}},
+		{"echo $P \"$P\"", []string{"echo", "c.txt", "*.txt"}},
+		{"echo '*.go' \\*.go", []string{"echo", "*.go", "*.go"}},
+		{"echo \\[lit].go", []string{"echo", "[lit].go"}},
+		{"echo *.none", []string{"echo", "*.none"}},
+	}
+
+	for _, test := range tests {
+		args, err := expandInput(t, sh, test.input)
+		assert.NoError(t, err, test.input)
+		assert.Equal(t, test.want, args, test.input)
+	}
+
+	assert.NoError(t, sh.setOption("nullglob", true))
+	args, err := expandInput(t, sh, "echo *.none")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"echo"}, args)
+
+	assert.NoError(t, sh.setOption("failglob", true))
+	_, err = expandInput(t, sh, "echo *.none")
+	assert.EqualError(t, err, "no match: *.none")
+
+	assert.NoError(t, sh.setOption("noglob", true))
+	args, err = expandInput(t, sh, "echo *.go")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"echo", "*.go"}, args)
+}
+
+func TestExpandCommandSubst(t *testing.T) {
+	sh := &shell{
+		jobs: &jobTable{},
+		std:  newStdio(os.Stdin, io.Discard, io.Discard),
+		vars: environVars([]string{"X=value"}),
+	}
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"echo $(echo a  b)", []string{"echo", "a", "b"}},
+		{"echo \"$(echo 'a  b')\"", []string{"echo", "a  b"}},
+		{"echo `echo tick`x", []string{"echo", "tickx"}},
+		{"echo $(echo \"nested $(echo $X)\")", []string{"echo", "nested", "value"}},
+		{"echo `echo \\`echo deep\\``", []string{"echo", "deep"}},
+		{"echo \"[$(echo a; echo; echo)]\"", []string{"echo", "[a]"}},
+		{"echo $(X=inner; echo $X) $X", []string{"echo", "inner", "value"}},
+		{"echo $( (echo sub) )", []string{"echo", "sub"}},
+	}
+
+	for _, test := range tests {
+		args, err := expandInput(t, sh, test.input)
+		assert.NoError(t, err, test.input)
+		assert.Equal(t, test.want, args, test.input)
+	}
+}
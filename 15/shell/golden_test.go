@@ -0,0 +1,57 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestGolden runs each testdata/*.sh in a fresh interpreter and compares
+// its stdout, stderr and exit code with the .golden file next to it. The
+// temporary working directory is shown as $WORK.
+func TestGolden(t *testing.T) {
+	scripts, err := filepath.Glob(filepath.Join("testdata", "*.sh"))
+	require.NoError(t, err)
+	require.NotEmpty(t, scripts)
+
+	for _, script := range scripts {
+		content, err := os.ReadFile(script)
+		require.NoError(t, err)
+
+		dir := t.TempDir()
+		var out, errOut bytes.Buffer
+		in, err := NewInterpreter(&Config{
+			Stdout: &out,
+			Stderr: &errOut,
+			Env:    []string{"HOME=" + dir, "PATH=" + os.Getenv("PATH"), "LC_ALL=C"},
+			Dir:    dir,
+		})
+		require.NoError(t, err)
+
+		code, err := in.Run(context.Background(), string(content))
+		require.NoError(t, err, script)
+
+		actual := fmt.Sprintf("-- stdout --\n%s-- stderr --\n%s-- exit --\n%d\n", out.String(), errOut.String(), code)
+		actual = strings.ReplaceAll(actual, dir, "$WORK")
+
+		golden := strings.TrimSuffix(script, ".sh") + ".golden"
+		if *update {
+			require.NoError(t, os.WriteFile(golden, []byte(actual), 0644))
+			continue
+		}
+
+		expected, err := os.ReadFile(golden)
+		require.NoError(t, err, "run go test -update to create %s", golden)
+		assert.Equal(t, string(expected), actual, script)
+	}
+}
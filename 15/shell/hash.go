@@ -0,0 +1,135 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// hashEntry is a remembered PATH lookup and the number of times the command
+// was run through it.
+type hashEntry struct {
+	path string
+	hits int
+}
+
+// syncHash drops the table when PATH has changed since it was filled.
+func (sh *shell) syncHash() {
+	if path := sh.getVar("PATH"); path != sh.hashPath {
+		sh.hash = nil
+		sh.hashPath = path
+	}
+}
+
+// hashed returns the remembered path of a command. An entry whose file is
+// gone is forgotten.
+func (sh *shell) hashed(name string) (string, bool) {
+	sh.syncHash()
+	entry, ok := sh.hash[name]
+	if !ok {
+		return "", false
+	}
+	if !isExecutable(entry.path) {
+		delete(sh.hash, name)
+		return "", false
+	}
+	return entry.path, true
+}
+
+func (sh *shell) remember(name, path string) {
+	sh.syncHash()
+	if sh.hash == nil {
+		sh.hash = make(map[string]hashEntry)
+	}
+	sh.hash[name] = hashEntry{path: path}
+}
+
+func (sh *shell) hashHit(name string) {
+	if entry, ok := sh.hash[name]; ok {
+		entry.hits++
+		sh.hash[name] = entry
+	}
+}
+
+func (sh *shell) hashBuiltin(args []string, std *stdio) error {
+	if len(args) == 0 {
+		return sh.printHash(std)
+	}
+
+	switch args[0] {
+	case "-r":
+		sh.hash = nil
+		return nil
+	case "-d":
+		var err error
+		for _, name := range args[1:] {
+			if _, ok := sh.hashed(name); !ok {
+				fmt.Fprintf(std.err(), "hash: %s: not found\n", name)
+				err = &statusError{code: 1}
+				continue
+			}
+			delete(sh.hash, name)
+		}
+		return err
+	case "-p":
+		if len(args) < 3 {
+			return errors.New("hash: usage: hash [-r] [-p path] [-dt] [name ...]")
+		}
+		path := sh.path(args[1])
+		for _, name := range args[2:] {
+			sh.remember(name, path)
+		}
+		return nil
+	case "-t":
+		var err error
+		for _, name := range args[1:] {
+			path, ok := sh.hashed(name)
+			switch {
+			case !ok:
+				fmt.Fprintf(std.err(), "hash: %s: not found\n", name)
+				err = &statusError{code: 1}
+			case len(args) > 2:
+				fmt.Fprintf(std.out(), "%s\t%s\n", name, path)
+			default:
+				fmt.Fprintln(std.out(), path)
+			}
+		}
+		return err
+	}
+
+	var err error
+	for _, name := range args {
+		if strings.Contains(name, "/") {
+			continue
+		}
+		if _, ok := sh.lookupBuiltin(name); ok {
+			continue
+		}
+		if _, lookErr := sh.lookPath(name); lookErr != nil {
+			fmt.Fprintf(std.err(), "hash: %s: not found\n", name)
+			err = &statusError{code: 1}
+		}
+	}
+	return err
+}
+
+func (sh *shell) printHash(std *stdio) error {
+	sh.syncHash()
+	if len(sh.hash) == 0 {
+		_, err := fmt.Fprintln(std.out(), "hash: hash table empty")
+		return err
+	}
+
+	names := make([]string, 0, len(sh.hash))
+	for name := range sh.hash {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(std.out(), "hits\tcommand")
+	for _, name := range names {
+		fmt.Fprintf(std.out(), "%4d\t%s\n", sh.hash[name].hits, sh.hash[name].path)
+	}
+	return nil
+}
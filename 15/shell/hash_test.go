@@ -0,0 +1,75 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHash(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "bin")
+	other := filepath.Join(dir, "other")
+	stubs := map[string]string{
+		"bin/stub":       "#!/bin/sh\necho stub $@\n",
+		"bin/tool":       "#!/bin/sh\necho tool\n",
+		"bin/noshebang":  "echo script $# $1 $X $Y\nexit 3\n",
+		"bin/badinterp":  "#!/nonexistent/interpreter\n",
+		"other/stub":     "#!/bin/sh\necho other stub\n",
+		"local/relative": "#!/bin/sh\necho relative $0\n",
+	}
+	for name, content := range stubs {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0755))
+	}
+
+	tests := []struct {
+		script string
+		out    string
+		errOut string
+		code   int
+	}{
+		{"hash", "hash: hash table empty\n", "", 0},
+		{"stub a; stub b; tool; hash", "stub a\nstub b\ntool\nhits\tcommand\n   2\t" + bin + "/stub\n   1\t" + bin + "/tool\n", "", 0},
+		{"hash stub; hash -t stub; hash", bin + "/stub\nhits\tcommand\n   0\t" + bin + "/stub\n", "", 0},
+		{"hash stub tool; hash -t stub tool", "stub\t" + bin + "/stub\ntool\t" + bin + "/tool\n", "", 0},
+		{"hash nope cd", "", "hash: nope: not found\n", 1},
+		{"stub; PATH=" + other + ":" + bin + "; stub; hash", "stub\nother stub\nhits\tcommand\n   1\t" + other + "/stub\n", "", 0},
+		{"stub; export PATH=" + other + "; tool", "stub\n", "tool: command not found\n", 127},
+		{"stub; hash -r; hash", "stub\nhash: hash table empty\n", "", 0},
+		{"stub; tool; hash -d stub; hash -d nope; hash", "stub\ntool\nhits\tcommand\n   1\t" + bin + "/tool\n", "hash: nope: not found\n", 0},
+		{"hash -t stub", "", "hash: stub: not found\n", 1},
+		{"hash -p " + other + "/stub stub; stub; hash -p /bin/echo say; say hi", "other stub\nhi\n", "", 0},
+		{"stub; mv " + bin + "/stub " + bin + "/moved; stub; mv " + bin + "/moved " + bin + "/stub", "stub\n", "stub: command not found\n", 0},
+		{"command -v stub cd; type tool", bin + "/stub\ncd\ntool is " + bin + "/tool\n", "", 0},
+		{"./local/relative; local/relative; cd local && ./relative", "relative ./local/relative\nrelative local/relative\nrelative ./relative\n", "", 0},
+		{"PATH=local:$PATH; relative; hash -t relative", "relative local/relative\n", "hash: relative: not found\n", 1},
+		{"f() { :; }; X=1; Y=2; export X; alias a=b; noshebang one two; echo $?", "script 2 one 1\n3\n", "", 0},
+		{"bin/noshebang; echo $?", "script 0\n3\n", "", 0},
+		{"badinterp; echo $?", "126\n", "badinterp: /nonexistent/interpreter: bad interpreter: No such file or directory\n", 0},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		in, err := NewInterpreter(&Config{
+			Stdout: &out,
+			Stderr: &errOut,
+			Env:    []string{"PATH=" + bin + ":" + os.Getenv("PATH")},
+			Dir:    dir,
+		})
+		require.NoError(t, err)
+
+		code, err := in.Run(context.Background(), test.script)
+		assert.NoError(t, err, test.script)
+		assert.Equal(t, test.code, code, test.script)
+		assert.Equal(t, test.out, strings.ReplaceAll(out.String(), ":"+os.Getenv("PATH"), ""), test.script)
+		assert.Equal(t, test.errOut, errOut.String(), test.script)
+	}
+}
@@ -0,0 +1,115 @@
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const defaultHistorySize = 1000
+
+// Entries are stored one per line of the history file, with backslashes
+// and newlines escaped so that multi-line commands survive a restart.
+var (
+	historyEscaper   = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	historyUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+)
+
+type history struct {
+	entries []string
+	path    string
+	size    int
+}
+
+func (sh *shell) historyPath() string {
+	if path := sh.getVar("HISTFILE"); path != "" {
+		return path
+	}
+
+	home := sh.homeDir()
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".l2sh_history")
+}
+
+func (sh *shell) historySize() int {
+	size, err := strconv.Atoi(sh.getVar("HISTSIZE"))
+	if err != nil || size < 0 {
+		return defaultHistorySize
+	}
+	return size
+}
+
+func loadHistory(path string, size int) *history {
+	h := &history{path: path, size: size}
+	if path == "" {
+		return h
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			h.entries = append(h.entries, historyUnescaper.Replace(line))
+		}
+	}
+	_ = file.Close()
+
+	if len(h.entries) > size {
+		h.entries = h.entries[len(h.entries)-size:]
+		h.save()
+	}
+
+	return h
+}
+
+func (h *history) add(line string) {
+	line = strings.TrimRight(line, "\n")
+	if strings.TrimSpace(line) == "" || strings.HasPrefix(line, " ") {
+		return
+	}
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return
+	}
+
+	h.entries = append(h.entries, line)
+	if len(h.entries) > h.size {
+		h.entries = h.entries[len(h.entries)-h.size:]
+	}
+
+	if h.path == "" {
+		return
+	}
+
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	_, _ = fmt.Fprintln(file, historyEscaper.Replace(line))
+	_ = file.Close()
+}
+
+func (h *history) save() {
+	var content strings.Builder
+	for _, entry := range h.entries {
+		content.WriteString(historyEscaper.Replace(entry) + "\n")
+	}
+	_ = os.WriteFile(h.path, []byte(content.String()), 0600)
+}
+
+func (h *history) search(query string, from int) int {
+	for i := min(from, len(h.entries)-1); i >= 0; i-- {
+		if strings.Contains(h.entries[i], query) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,75 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryAdd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h := loadHistory(path, 3)
+
+	for _, line := range []string{"ls", "ls", "", " secret", "pwd", "echo a", "echo b"} {
+		h.add(line)
+	}
+	assert.Equal(t, []string{"pwd", "echo a", "echo b"}, h.entries)
+
+	reloaded := loadHistory(path, 3)
+	assert.Equal(t, []string{"pwd", "echo a", "echo b"}, reloaded.entries)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "pwd\necho a\necho b\n", string(data))
+}
+
+func TestHistorySearch(t *testing.T) {
+	h := &history{entries: []string{"echo one", "ls", "echo two"}}
+
+	tests := []struct {
+		query string
+		from  int
+		want  int
+	}{
+		{"echo", 2, 2},
+		{"echo", 1, 0},
+		{"ls", 2, 1},
+		{"cat", 2, -1},
+		{"one", 10, 0},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, h.search(test.query, test.from))
+	}
+}
+
+func TestHistoryMultiline(t *testing.T) {
+	tests := []struct {
+		line string
+		file string
+	}{
+		{"for i in 1 2\ndo\n  echo $i\ndone", `for i in 1 2\ndo\n  echo $i\ndone`},
+		{"cat <<EOF\na\nEOF\n", `cat <<EOF\na\nEOF`},
+		{`echo a\nb \\`, `echo a\\nb \\\\`},
+		{"echo 'a\\\nb'", `echo 'a\\\nb'`},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "history")
+		loadHistory(path, 10).add(test.line)
+
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, test.file+"\n", string(data), test.line)
+
+		want := strings.TrimRight(test.line, "\n")
+		assert.Equal(t, []string{want}, loadHistory(path, 10).entries, test.line)
+
+		saved := &history{entries: []string{"ls", want}, path: path, size: 10}
+		saved.save()
+		assert.Equal(t, saved.entries, loadHistory(path, 10).entries, "%s: saved", test.line)
+	}
+}
@@ -0,0 +1,82 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Config describes the environment of an Interpreter. Nil streams read
+// nothing and discard output, Env holds the exported variables in
+// "NAME=value" form, and an empty Dir is the current directory.
+type Config struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Env    []string
+	Dir    string
+	Name   string
+	Args   []string
+}
+
+// Interpreter runs scripts in a shell whose variables, functions, aliases
+// and working directory persist between runs. It never changes the
+// environment or directory of the process, so several interpreters can run
+// at the same time; calls to Run on one interpreter are serialized.
+type Interpreter struct {
+	sh    *shell
+	mutex sync.Mutex
+}
+
+func NewInterpreter(config *Config) (*Interpreter, error) {
+	dir, err := filepath.Abs(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s: not a directory", dir)
+	}
+
+	cfg := *config
+	cfg.Dir = dir
+	return &Interpreter{sh: newShell(&cfg)}, nil
+}
+
+// Run executes script and returns its exit status. An EXIT trap set by the
+// script runs when it finishes. When ctx is done the running commands are
+// killed, the script stops and ctx.Err() is returned along with the status.
+func (in *Interpreter) Run(ctx context.Context, script string) (int, error) {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	in.sh.ctx = ctx
+	defer func() {
+		in.sh.ctx = context.Background()
+	}()
+
+	status := exitCode(in.sh.runExitTrap(in.sh.runInput(lineReader(strings.NewReader(script)), false)))
+	in.sh.status = status
+	return status, ctx.Err()
+}
+
+// Dir returns the working directory of the interpreter.
+func (in *Interpreter) Dir() string {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+	return in.sh.dir
+}
+
+// Getenv returns the value of a shell variable.
+func (in *Interpreter) Getenv(name string) string {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+	return in.sh.getVar(name)
+}
@@ -0,0 +1,149 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpreterRun(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "file"), []byte("content\n"), 0644))
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tests := []struct {
+		script string
+		stdin  string
+		out    string
+		errOut string
+		code   int
+	}{
+		{"pwd", "", dir + "\n", "", 0},
+		{"echo $GREETING; env | grep -c GREETING=hi", "", "hi\n1\n", "", 0},
+		{"cd sub && pwd && cat file && ls", "", dir + "/sub\ncontent\nfile\n", "", 0},
+		{"cat sub/file; builtin grep cont sub/file; test -f sub/file && echo found", "", "content\ncontent\nfound\n", "", 0},
+		{"echo sub/*; cd sub; echo *", "", "sub/file\nfile\n", "", 0},
+		{"(cd sub); pwd; echo $(cd sub; pwd)", "", dir + "\n" + dir + "/sub\n", "", 0},
+		{"echo out > sub/new; cat < sub/new", "", "out\n", "", 0},
+		{"cat; echo $1", "from stdin\n", "from stdin\narg\n", "", 0},
+		{"cd nope", "", "", "chdir nope: no such file or directory\n", 1},
+		{"exit 3", "", "", "", 3},
+		{"if", "", "", "script: syntax error: unexpected end of file\n", 2},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		in, err := NewInterpreter(&Config{
+			Stdin:  strings.NewReader(test.stdin),
+			Stdout: &out,
+			Stderr: &errOut,
+			Env:    []string{"GREETING=hi", "PATH=" + os.Getenv("PATH")},
+			Dir:    dir,
+			Name:   "script",
+			Args:   []string{"arg"},
+		})
+		require.NoError(t, err)
+
+		code, err := in.Run(context.Background(), test.script)
+		assert.NoError(t, err, test.script)
+		assert.Equal(t, test.code, code, test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Equal(t, test.errOut, errOut.String(), test.script)
+	}
+
+	current, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, cwd, current)
+}
+
+func TestInterpreterState(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+
+	var out bytes.Buffer
+	in, err := NewInterpreter(&Config{Stdout: &out, Dir: dir})
+	require.NoError(t, err)
+
+	code, err := in.Run(context.Background(), "X=1; f() { echo f $X; }; alias a='echo alias'; cd sub; false")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, filepath.Join(dir, "sub"), in.Dir())
+	assert.Equal(t, "1", in.Getenv("X"))
+
+	code, err = in.Run(context.Background(), "echo $?; f; a; pwd")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "1\nf 1\nalias\n"+dir+"/sub\n", out.String())
+
+	_, err = NewInterpreter(&Config{Dir: filepath.Join(dir, "nope")})
+	assert.Error(t, err)
+}
+
+func TestInterpreterConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := range 8 {
+		dir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var out bytes.Buffer
+			in, err := NewInterpreter(&Config{
+				Stdout: &out,
+				Env:    []string{"N=" + fmt.Sprint(i), "PATH=" + os.Getenv("PATH")},
+				Dir:    dir,
+			})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			script := "for k in 1 2 3; do cd sub; echo $N $(pwd) | cat; cd ..; done"
+			code, err := in.Run(context.Background(), script)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, code)
+			line := fmt.Sprintf("%d %s/sub\n", i, dir)
+			assert.Equal(t, strings.Repeat(line, 3), out.String())
+		}()
+	}
+	wg.Wait()
+}
+
+func TestInterpreterCancel(t *testing.T) {
+	tests := []string{
+		"sleep 10",
+		"sleep 10 | cat",
+		"while true; do :; done",
+		"until false; do true; done; echo unreachable",
+		"echo $(sleep 10)",
+		"sleep 10 & wait",
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		in, err := NewInterpreter(&Config{Stdout: &out, Env: []string{"PATH=" + os.Getenv("PATH")}})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		start := time.Now()
+		code, err := in.Run(ctx, test)
+		cancel()
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded, test)
+		assert.NotEqual(t, 0, code, test)
+		assert.Less(t, time.Since(start), 5*time.Second, test)
+		assert.Empty(t, out.String(), test)
+	}
+}
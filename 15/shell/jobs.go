@@ -0,0 +1,574 @@
+package shell
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+type jobState int
+
+const (
+	jobRunning jobState = iota
+	jobStopped
+	jobDone
+)
+
+type job struct {
+	id         int
+	pgid       int
+	pids       map[int]bool
+	lastPid    int
+	command    string
+	state      jobState
+	err        error
+	foreground bool
+	done       chan struct{}
+	launched   chan struct{}
+	mutex      sync.Mutex
+	startMutex sync.Mutex
+}
+
+type jobTable struct {
+	jobs  []*job
+	mutex sync.Mutex
+}
+
+func newJob(command string) *job {
+	return &job{
+		command:  command,
+		pids:     make(map[int]bool),
+		done:     make(chan struct{}),
+		launched: make(chan struct{}),
+	}
+}
+
+func (j *job) processGroup() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.pgid
+}
+
+func (j *job) started(pid int, group bool) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if group && len(j.pids) == 0 {
+		j.pgid = pid
+	}
+	if j.lastPid == 0 {
+		close(j.launched)
+	}
+	j.pids[pid] = true
+	j.lastPid = pid
+}
+
+func (j *job) pid() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.lastPid
+}
+
+func (j *job) exited(pid int) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	delete(j.pids, pid)
+	if len(j.pids) == 0 {
+		j.pgid = 0
+	}
+}
+
+func (j *job) finish(err error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.state = jobDone
+	j.err = err
+	close(j.done)
+}
+
+func (j *job) setState(state jobState) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.state != jobDone {
+		j.state = state
+	}
+}
+
+func (j *job) currentState() jobState {
+	j.mutex.Lock()
+	state := j.state
+	j.mutex.Unlock()
+
+	if state == jobRunning && j.isStopped() {
+		return jobStopped
+	}
+	return state
+}
+
+func (j *job) signal(sig syscall.Signal) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.pgid != 0 {
+		return syscall.Kill(-j.pgid, sig)
+	}
+
+	var err error
+	for pid := range j.pids {
+		if e := syscall.Kill(pid, sig); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (j *job) isStopped() bool {
+	j.mutex.Lock()
+	pids := make([]int, 0, len(j.pids))
+	for pid := range j.pids {
+		pids = append(pids, pid)
+	}
+	j.mutex.Unlock()
+
+	for _, pid := range pids {
+		if processState(pid) == 'T' {
+			return true
+		}
+	}
+	return false
+}
+
+func processState(pid int) byte {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return 0
+	}
+
+	stat := string(data)
+	i := strings.LastIndexByte(stat, ')')
+	if i < 0 || i+2 >= len(stat) {
+		return 0
+	}
+	return stat[i+2]
+}
+
+func (t *jobTable) add(j *job) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	id := 1
+	for _, other := range t.jobs {
+		id = max(id, other.id+1)
+	}
+	j.id = id
+	t.jobs = append(t.jobs, j)
+}
+
+func (t *jobTable) remove(j *job) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for i, other := range t.jobs {
+		if other == j {
+			t.jobs = append(t.jobs[:i], t.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *jobTable) list() []*job {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return append([]*job(nil), t.jobs...)
+}
+
+func (t *jobTable) find(spec string) (*job, error) {
+	jobs := t.list()
+	if len(jobs) == 0 {
+		return nil, errors.New("no such job")
+	}
+
+	spec = strings.TrimPrefix(spec, "%")
+
+	switch spec {
+	case "", "%", "+":
+		return jobs[len(jobs)-1], nil
+	case "-":
+		if len(jobs) < 2 {
+			return jobs[len(jobs)-1], nil
+		}
+		return jobs[len(jobs)-2], nil
+	}
+
+	if id, err := strconv.Atoi(spec); err == nil {
+		for _, j := range jobs {
+			if j.id == id {
+				return j, nil
+			}
+		}
+		return nil, fmt.Errorf("%%%s: no such job", spec)
+	}
+
+	for i := len(jobs) - 1; i >= 0; i-- {
+		if strings.HasPrefix(jobs[i].command, spec) {
+			return jobs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%%%s: no such job", spec)
+}
+
+func (t *jobTable) findByPid(pid int) *job {
+	for _, j := range t.list() {
+		j.mutex.Lock()
+		found := j.pids[pid]
+		j.mutex.Unlock()
+		if found || j.pid() == pid {
+			return j
+		}
+	}
+	return nil
+}
+
+func (t *jobTable) format(j *job) string {
+	jobs := t.list()
+
+	marker := " "
+	if len(jobs) > 0 && jobs[len(jobs)-1] == j {
+		marker = "+"
+	} else if len(jobs) > 1 && jobs[len(jobs)-2] == j {
+		marker = "-"
+	}
+
+	var status string
+	command := j.command
+	switch j.currentState() {
+	case jobRunning:
+		status = "Running"
+		command += " &"
+	case jobStopped:
+		status = "Stopped"
+	default:
+		status = "Done"
+		if signaled, ok := signalStatus(j.err); ok {
+			status = describeSignal(signaled)
+		} else if code := exitCode(j.err); code != 0 {
+			status = "Exit " + strconv.Itoa(code)
+		}
+	}
+
+	return fmt.Sprintf("[%d]%s  %-24s%s", j.id, marker, status, command)
+}
+
+func (t *jobTable) notify(w io.Writer) {
+	for _, j := range t.list() {
+		if j.currentState() == jobDone {
+			fmt.Fprintln(w, t.format(j))
+			t.remove(j)
+		}
+	}
+}
+
+func (sh *shell) initJobControl() {
+	sh.ttyFd = int(os.Stdin.Fd())
+	sh.interactive = term.IsTerminal(sh.ttyFd)
+	if !sh.interactive {
+		return
+	}
+
+	sh.pgid = syscall.Getpgrp()
+
+	signal.Ignore(syscall.SIGTTOU)
+	signal.Notify(make(chan os.Signal, 1), syscall.SIGQUIT, syscall.SIGTSTP, syscall.SIGTTIN)
+}
+
+// inForeground reports whether the process is in the foreground process
+// group of its terminal, where keyboard signals also reach its children.
+func inForeground() bool {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return false
+	}
+	defer tty.Close()
+
+	pgrp, err := unix.IoctlGetInt(int(tty.Fd()), unix.TIOCGPGRP)
+	return err == nil && pgrp == syscall.Getpgrp()
+}
+
+func (sh *shell) giveTerminal(pgid int) {
+	if !sh.interactive || pgid == 0 {
+		return
+	}
+	_ = unix.IoctlSetPointerInt(sh.ttyFd, unix.TIOCSPGRP, pgid)
+}
+
+func (sh *shell) startProcess(cmd *exec.Cmd, j *job, foreground bool) error {
+	// Pipeline stages start their processes concurrently; the first one
+	// must be registered as the group leader before the next is forked.
+	j.startMutex.Lock()
+	defer j.startMutex.Unlock()
+
+	if sh.interactive {
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Setpgid:    true,
+			Pgid:       j.processGroup(),
+			Foreground: foreground || j.foreground,
+			Ctty:       sh.ttyFd,
+		}
+	}
+
+	err := cmd.Start()
+	if err != nil {
+		return sh.startError(cmd, err)
+	}
+
+	j.started(cmd.Process.Pid, sh.interactive)
+	return nil
+}
+
+// startError describes why a command could not be run, with status 127 if
+// it does not exist and 126 if it is not executable.
+func (sh *shell) startError(cmd *exec.Cmd, err error) error {
+	name := cmd.Args[0]
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		return &statusError{code: 127, message: name + ": command not found"}
+	case errors.Is(err, syscall.ENOENT):
+		if interpreter := scriptInterpreter(sh.path(cmd.Path)); interpreter != "" {
+			return &statusError{code: 126, message: name + ": " + interpreter + ": bad interpreter: No such file or directory"}
+		}
+		return &statusError{code: 127, message: name + ": No such file or directory"}
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		if info, statErr := os.Stat(sh.path(cmd.Path)); statErr == nil && info.IsDir() {
+			return &statusError{code: 126, message: name + ": Is a directory"}
+		}
+		return &statusError{code: 126, message: name + ": Permission denied"}
+	case errors.Is(err, syscall.ENOEXEC):
+		return &statusError{code: 126, message: name + ": cannot execute binary file: Exec format error"}
+	}
+	return &statusError{code: 126, message: name + ": " + err.Error()}
+}
+
+// scriptInterpreter returns the interpreter named on the #! line of path.
+func scriptInterpreter(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	line, _ := bufio.NewReader(file).ReadString('\n')
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+	fields := strings.Fields(line[2:])
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// signalStatus returns the wait status of a command killed by a signal.
+func signalStatus(err error) (syscall.WaitStatus, bool) {
+	var exitError *exec.ExitError
+	if !errors.As(err, &exitError) {
+		return 0, false
+	}
+	status, ok := exitError.Sys().(syscall.WaitStatus)
+	return status, ok && status.Signaled()
+}
+
+// describeSignal returns a message such as "Terminated" or "Segmentation
+// fault (core dumped)".
+func describeSignal(status syscall.WaitStatus) string {
+	desc := status.Signal().String()
+	desc = strings.ToUpper(desc[:1]) + desc[1:]
+	if status.CoreDump() {
+		desc += " (core dumped)"
+	}
+	return desc
+}
+
+func (sh *shell) runProcesses(cmds []*exec.Cmd, text string, afterStart func(), status func([]error) error) error {
+	j := sh.job
+	foreground := j == nil
+	if foreground {
+		j = newJob(text)
+	}
+
+	var startErr error
+	var started []*exec.Cmd
+
+	for _, cmd := range cmds {
+		if err := sh.startProcess(cmd, j, foreground); err != nil {
+			startErr = err
+			break
+		}
+		started = append(started, cmd)
+	}
+
+	if afterStart != nil {
+		afterStart()
+	}
+
+	stop := func() bool { return false }
+	if sh.ctx != nil {
+		stop = context.AfterFunc(sh.ctx, func() {
+			_ = j.signal(syscall.SIGKILL)
+		})
+	}
+
+	errs := make([]error, len(cmds))
+	var wg sync.WaitGroup
+
+	for i, cmd := range started {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = cmd.Wait()
+			j.exited(cmd.Process.Pid)
+		}()
+	}
+
+	result := func() error {
+		wg.Wait()
+		stop()
+		if startErr != nil {
+			return startErr
+		}
+		return status(errs)
+	}
+
+	if !foreground {
+		return result()
+	}
+
+	go func() {
+		j.finish(result())
+	}()
+
+	return sh.waitForeground(j)
+}
+
+func (sh *shell) waitForeground(j *job) error {
+	sigchld := make(chan os.Signal, 1)
+	signal.Notify(sigchld, syscall.SIGCHLD)
+	defer signal.Stop(sigchld)
+
+	// Keyboard signals reach the job through the terminal; others sent to
+	// the shell itself are passed on while it waits.
+	var forward chan os.Signal
+	if sh.forwardSignals {
+		forward = make(chan os.Signal, 1)
+		signal.Notify(forward, syscall.SIGINT, syscall.SIGQUIT)
+		defer signal.Stop(forward)
+	}
+
+	defer sh.giveTerminal(sh.pgid)
+
+	for {
+		if j.isStopped() {
+			j.setState(jobStopped)
+			if j.id == 0 {
+				sh.jobs.add(j)
+			}
+			fmt.Fprintf(sh.std.err(), "\n%s\n", sh.jobs.format(j))
+			return &statusError{code: 128 + int(syscall.SIGTSTP)}
+		}
+
+		select {
+		case <-j.done:
+			status, ok := signalStatus(j.err)
+			if ok && status.Signal() != syscall.SIGINT && status.Signal() != syscall.SIGPIPE {
+				fmt.Fprintln(sh.std.err(), describeSignal(status))
+			}
+			// Like the shell itself, a script stops when its foreground
+			// command is interrupted, unless SIGINT is trapped.
+			if _, trapped := sh.traps[syscall.SIGINT]; ok && status.Signal() == syscall.SIGINT && !sh.interactive && !trapped {
+				return &shellExit{code: 128 + int(syscall.SIGINT)}
+			}
+			return j.err
+		case <-sigchld:
+		case sig := <-forward:
+			_ = j.signal(sig.(syscall.Signal))
+		}
+	}
+}
+
+// waitRunning waits for j to finish and reports whether it did, or false
+// once j is stopped.
+func waitRunning(j *job) bool {
+	sigchld := make(chan os.Signal, 1)
+	signal.Notify(sigchld, syscall.SIGCHLD)
+	defer signal.Stop(sigchld)
+
+	for {
+		if j.currentState() == jobStopped {
+			return false
+		}
+		select {
+		case <-j.done:
+			return true
+		case <-sigchld:
+		}
+	}
+}
+
+func (sh *shell) startBackground(item *listItem, std *stdio) {
+	j := newJob(item.text)
+	sh.jobs.add(j)
+
+	bg := sh.subshell()
+	bg.job = j
+	// Asynchronous commands are not stopped by an interrupt of the script.
+	bg.interrupted = nil
+
+	bgStd := std.clone()
+	var devNull *os.File
+	if !sh.interactive {
+		file, err := os.Open(os.DevNull)
+		if err == nil {
+			devNull = file
+			bgStd.set(0, devNull)
+		}
+	}
+
+	go func() {
+		err := bg.executeConditionalCommands(item.commands, bgStd)
+		if devNull != nil {
+			_ = devNull.Close()
+		}
+		j.finish(err)
+	}()
+
+	select {
+	case <-j.launched:
+	case <-j.done:
+	}
+
+	sh.lastBgPid = j.pid()
+	if !sh.interactive {
+		return
+	}
+
+	if sh.lastBgPid != 0 {
+		fmt.Fprintf(std.err(), "[%d] %d\n", j.id, sh.lastBgPid)
+	} else {
+		fmt.Fprintf(std.err(), "[%d]\n", j.id)
+	}
+}
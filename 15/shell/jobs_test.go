@@ -0,0 +1,182 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobTableFind(t *testing.T) {
+	table := &jobTable{}
+	first := newJob("sleep 100")
+	second := newJob("cat file")
+	third := newJob("sleep 5")
+	table.add(first)
+	table.add(second)
+	table.add(third)
+
+	tests := []struct {
+		spec    string
+		want    *job
+		wantErr bool
+	}{
+		{"", third, false},
+		{"%%", third, false},
+		{"%+", third, false},
+		{"%-", second, false},
+		{"%1", first, false},
+		{"2", second, false},
+		{"%cat", second, false},
+		{"%sleep", third, false},
+		{"%7", nil, true},
+		{"%vim", nil, true},
+	}
+
+	for _, test := range tests {
+		res, err := table.find(test.spec)
+		assert.Equal(t, test.wantErr, err != nil, test.spec)
+		assert.Equal(t, test.want, res, test.spec)
+	}
+}
+
+func TestJobTableIDs(t *testing.T) {
+	table := &jobTable{}
+	first := newJob("a")
+	second := newJob("b")
+	table.add(first)
+	table.add(second)
+	table.remove(first)
+
+	third := newJob("c")
+	table.add(third)
+	assert.Equal(t, 3, third.id)
+
+	table.remove(second)
+	table.remove(third)
+
+	fourth := newJob("d")
+	table.add(fourth)
+	assert.Equal(t, 1, fourth.id)
+}
+
+func TestJobTableFormat(t *testing.T) {
+	table := &jobTable{}
+	running := newJob("sleep 100")
+	done := newJob("false")
+	table.add(running)
+	table.add(done)
+	done.finish(&statusError{code: 1})
+
+	assert.Equal(t, "[1]-  Running                 sleep 100 &", table.format(running))
+	assert.Equal(t, "[2]+  Exit 1                  false", table.format(done))
+
+	var out strings.Builder
+	table.notify(&out)
+	assert.Equal(t, "[2]+  Exit 1                  false\n", out.String())
+	assert.Len(t, table.list(), 1)
+}
+
+func TestCommandDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "plain"), []byte("echo plain\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "garbage"), []byte{0x7f, 'E', 'L', 'F', 0, 1}, 0755))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+
+	tests := []struct {
+		script string
+		out    string
+		errOut string
+	}{
+		{"nosuchcommand; echo $?", "127\n", "nosuchcommand: command not found\n"},
+		{"./missing; echo $?", "127\n", "./missing: No such file or directory\n"},
+		{"./plain; echo $?", "126\n", "./plain: Permission denied\n"},
+		{"./sub; echo $?", "126\n", "./sub: Is a directory\n"},
+		{"./garbage; echo $?", "126\n", "./garbage: cannot execute binary file: Exec format error\n"},
+		{"sh -c 'kill -TERM $$'; echo $?", "143\n", "Terminated\n"},
+		{"trap : INT; sh -c 'kill -INT $$'; echo $?", "130\n", ""},
+		{"sh -c 'kill -KILL $$' | cat; echo $?", "0\n", ""},
+		{"set -o pipefail; true | sh -c 'kill -KILL $$'; echo $?", "137\n", "Killed\n"},
+		{"sleep 10 & kill -KILL %1; wait %1; echo $?", "137\n", ""},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		in, err := NewInterpreter(&Config{
+			Stdout: &out,
+			Stderr: &errOut,
+			Env:    []string{"PATH=" + os.Getenv("PATH")},
+			Dir:    dir,
+		})
+		assert.NoError(t, err)
+
+		_, err = in.Run(context.Background(), test.script)
+		assert.NoError(t, err, test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Equal(t, test.errOut, errOut.String(), test.script)
+	}
+}
+
+func TestWaitSkipsStoppedJobs(t *testing.T) {
+	tests := []struct {
+		script string
+		out    string
+	}{
+		{"sleep 10 & kill -STOP $!; sleep 0.2; wait; echo waited; kill -KILL %1", "waited\n"},
+		{"sleep 10 & (sleep 0.2; kill -STOP $!) & wait; echo waited; jobs; kill -KILL %1", "waited\n[1]+  Stopped                 sleep 10\n"},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		in, err := NewInterpreter(&Config{Stdout: &out, Env: []string{"PATH=" + os.Getenv("PATH")}})
+		assert.NoError(t, err)
+
+		start := time.Now()
+		_, err = in.Run(context.Background(), test.script)
+		assert.NoError(t, err, test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Less(t, time.Since(start), 5*time.Second, test.script)
+	}
+}
+
+func TestForwardSignals(t *testing.T) {
+	ignored := make(chan os.Signal, 1)
+	signal.Notify(ignored, syscall.SIGINT, syscall.SIGQUIT)
+	defer signal.Stop(ignored)
+
+	tests := []struct {
+		sig    syscall.Signal
+		script string
+		out    string
+		code   int
+	}{
+		{syscall.SIGINT, "sleep 10; echo $?", "", 130},
+		{syscall.SIGINT, "trap 'echo trapped' INT; sleep 10; echo $?", "trapped\n130\n", 0},
+		{syscall.SIGQUIT, "sleep 10; echo $?", "131\n", 0},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		in, err := NewInterpreter(&Config{Stdout: &out, Env: []string{"PATH=" + os.Getenv("PATH")}})
+		assert.NoError(t, err)
+		in.sh.forwardSignals = true
+
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			_ = syscall.Kill(os.Getpid(), test.sig)
+		}()
+		start := time.Now()
+		code, err := in.Run(context.Background(), test.script)
+		assert.NoError(t, err)
+		assert.Equal(t, test.code, code, test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Less(t, time.Since(start), 5*time.Second, test.script)
+	}
+}
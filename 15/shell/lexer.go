@@ -0,0 +1,714 @@
+package shell
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenOperator
+	tokenIONumber
+	tokenNewline
+	tokenEOF
+)
+
+type token struct {
+	kind    tokenKind
+	text    string
+	word    *word
+	heredoc *heredoc
+	pos     int
+	end     int
+}
+
+type wordPart struct {
+	text    string
+	quoted  bool
+	param   *paramExp
+	command *commandList
+}
+
+func (p wordPart) expands() bool {
+	return p.param != nil || p.command != nil
+}
+
+type heredoc struct {
+	delimiter string
+	strip     bool
+	body      *word
+}
+
+func (h *heredoc) unterminated() error {
+	return &incompleteError{message: fmt.Sprintf("here-document delimited by end-of-file (wanted `%s')", h.delimiter)}
+}
+
+type incompleteError struct {
+	message string
+}
+
+func (e *incompleteError) Error() string {
+	return e.message
+}
+
+type paramExp struct {
+	name string
+	op   string
+	arg  *word
+}
+
+type word struct {
+	parts []wordPart
+	raw   string
+}
+
+func (w *word) String() string {
+	var sb strings.Builder
+	for _, part := range w.parts {
+		sb.WriteString(part.text)
+	}
+	return sb.String()
+}
+
+func (w *word) literal() (string, bool) {
+	var sb strings.Builder
+	for _, part := range w.parts {
+		if part.quoted || part.expands() {
+			return "", false
+		}
+		sb.WriteString(part.text)
+	}
+	return sb.String(), true
+}
+
+func (w *word) isQuoted() bool {
+	for _, part := range w.parts {
+		if part.quoted {
+			return true
+		}
+	}
+	return false
+}
+
+var shellOperators = []string{"<<<", "<<-", "&>>", "&&", "||", ";;", ">>", "<<", ">&", "<&", ">|", "<>", "&>", ";", "&", "|", "(", ")", "<", ">"}
+
+type lexer struct {
+	input     []rune
+	pos       int
+	heredocs  []*heredoc
+	delimiter string
+	aliases   map[string]string
+}
+
+func tokenize(input string) ([]token, error) {
+	return tokenizeWithAliases(input, nil)
+}
+
+// tokenizeWithAliases tokenizes input; the aliases are passed on to the
+// parser of any command substitutions it contains.
+func tokenizeWithAliases(input string, aliases map[string]string) ([]token, error) {
+	l := &lexer{input: []rune(input), aliases: aliases}
+
+	var tokens []token
+	for {
+		l.skipBlanks()
+		start := l.pos
+
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tok.pos = start
+		tok.end = l.pos
+		tokens = append(tokens, tok)
+		if tok.kind == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *lexer) eof() bool {
+	return l.pos >= len(l.input)
+}
+
+func (l *lexer) peek() rune {
+	if l.eof() {
+		return 0
+	}
+	return l.input[l.pos]
+}
+
+func (l *lexer) next() (token, error) {
+	if l.peek() == '#' {
+		for !l.eof() && l.peek() != '\n' {
+			l.pos++
+		}
+	}
+
+	if l.eof() {
+		if len(l.heredocs) > 0 {
+			return token{}, l.heredocs[0].unterminated()
+		}
+		return token{kind: tokenEOF}, nil
+	}
+
+	if l.peek() == '\n' {
+		l.pos++
+		if err := l.readHeredocs(); err != nil {
+			return token{}, err
+		}
+		return token{kind: tokenNewline, text: "\n"}, nil
+	}
+
+	if op := l.matchOperator(); op != "" {
+		l.pos += len([]rune(op))
+		l.delimiter = ""
+		if op == "<<" || op == "<<-" {
+			l.delimiter = op
+		}
+		return token{kind: tokenOperator, text: op}, nil
+	}
+
+	tok, err := l.readWord()
+	if err != nil || l.delimiter == "" || tok.kind != tokenWord {
+		return tok, err
+	}
+
+	tok.heredoc = &heredoc{delimiter: tok.word.String(), strip: l.delimiter == "<<-"}
+	if !tok.word.isQuoted() {
+		tok.heredoc.body = &word{}
+	}
+	l.heredocs = append(l.heredocs, tok.heredoc)
+	l.delimiter = ""
+
+	return tok, nil
+}
+
+func (l *lexer) readHeredocs() error {
+	for len(l.heredocs) > 0 {
+		h := l.heredocs[0]
+
+		var body strings.Builder
+		for {
+			if l.eof() {
+				return h.unterminated()
+			}
+
+			start := l.pos
+			for !l.eof() && l.peek() != '\n' {
+				l.pos++
+			}
+			line := string(l.input[start:l.pos])
+			if !l.eof() {
+				l.pos++
+			}
+
+			if h.strip {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if line == h.delimiter {
+				break
+			}
+			body.WriteString(line)
+			body.WriteString("\n")
+		}
+
+		if h.body == nil {
+			h.body = &word{parts: []wordPart{{text: body.String(), quoted: true}}}
+		} else {
+			w, err := lexHeredoc(body.String())
+			if err != nil {
+				return err
+			}
+			h.body = w
+		}
+		l.heredocs = l.heredocs[1:]
+	}
+
+	return nil
+}
+
+func (l *lexer) skipBlanks() {
+	for !l.eof() {
+		c := l.peek()
+		if c == ' ' || c == '\t' {
+			l.pos++
+			continue
+		}
+		if c == '\\' && l.pos+1 < len(l.input) && l.input[l.pos+1] == '\n' {
+			l.pos += 2
+			continue
+		}
+		return
+	}
+}
+
+func (l *lexer) matchOperator() string {
+	rest := string(l.input[l.pos:min(l.pos+3, len(l.input))])
+	for _, op := range shellOperators {
+		if strings.HasPrefix(rest, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isWordBreak(c rune) bool {
+	return c == ' ' || c == '\t' || c == '\n' || strings.ContainsRune(";&|()<>", c)
+}
+
+type wordBuilder struct {
+	w      *word
+	buf    strings.Builder
+	quoted bool
+}
+
+func (b *wordBuilder) flush() {
+	if b.buf.Len() > 0 {
+		b.w.parts = append(b.w.parts, wordPart{text: b.buf.String(), quoted: b.quoted})
+		b.buf.Reset()
+	}
+}
+
+func (b *wordBuilder) write(s string, quoted bool) {
+	if b.quoted != quoted {
+		b.flush()
+		b.quoted = quoted
+	}
+	b.buf.WriteString(s)
+}
+
+func (b *wordBuilder) add(part wordPart) {
+	b.flush()
+	b.w.parts = append(b.w.parts, part)
+}
+
+func (l *lexer) readWord() (token, error) {
+	start := l.pos
+
+	w, err := l.readWordParts(isWordBreak)
+	if err != nil {
+		return token{}, err
+	}
+
+	text := string(l.input[start:l.pos])
+	if isNumber(text) && !l.eof() && (l.peek() == '<' || l.peek() == '>') {
+		return token{kind: tokenIONumber, text: text}, nil
+	}
+
+	return token{kind: tokenWord, text: text, word: w}, nil
+}
+
+func lexWord(text string) (*word, error) {
+	l := &lexer{input: []rune(text)}
+	return l.readWordParts(func(rune) bool {
+		return false
+	})
+}
+
+func (l *lexer) readWordParts(isBreak func(rune) bool) (*word, error) {
+	b := &wordBuilder{w: &word{}}
+	start := l.pos
+
+	for !l.eof() {
+		c := l.peek()
+		if isBreak(c) {
+			break
+		}
+
+		switch c {
+		case '\\':
+			l.pos++
+			if l.eof() {
+				return nil, &incompleteError{message: "syntax error: unexpected end of file"}
+			}
+			escaped := l.peek()
+			l.pos++
+			if escaped == '\n' {
+				continue
+			}
+			b.write(string(escaped), true)
+		case '\'':
+			l.pos++
+			start := l.pos
+			for !l.eof() && l.peek() != '\'' {
+				l.pos++
+			}
+			if l.eof() {
+				return nil, &incompleteError{message: "syntax error: unterminated single quote"}
+			}
+			b.write(string(l.input[start:l.pos]), true)
+			l.pos++
+			if start == l.pos-1 {
+				b.add(wordPart{quoted: true})
+			}
+		case '"':
+			l.pos++
+			if err := l.readDoubleQuoted(b); err != nil {
+				return nil, err
+			}
+		case '$':
+			if err := l.readDollar(b, false); err != nil {
+				return nil, err
+			}
+		case '`':
+			list, err := l.readBackquote(false)
+			if err != nil {
+				return nil, err
+			}
+			b.add(wordPart{command: list})
+		default:
+			b.write(string(c), false)
+			l.pos++
+		}
+	}
+	b.flush()
+
+	if len(b.w.parts) == 0 {
+		b.w.parts = append(b.w.parts, wordPart{quoted: true})
+	}
+	b.w.raw = string(l.input[start:l.pos])
+
+	return b.w, nil
+}
+
+func (l *lexer) readDoubleQuoted(b *wordBuilder) error {
+	empty := true
+
+	for !l.eof() {
+		c := l.peek()
+		switch c {
+		case '"':
+			l.pos++
+			if empty {
+				b.add(wordPart{quoted: true})
+			}
+			return nil
+		case '\\':
+			l.pos++
+			if l.eof() {
+				b.write("\\", true)
+				continue
+			}
+			escaped := l.peek()
+			switch escaped {
+			case '\n':
+				l.pos++
+			case '\\', '"', '$', '`':
+				l.pos++
+				b.write(string(escaped), true)
+			default:
+				b.write("\\", true)
+			}
+		case '$':
+			if err := l.readDollar(b, true); err != nil {
+				return err
+			}
+		case '`':
+			list, err := l.readBackquote(true)
+			if err != nil {
+				return err
+			}
+			b.add(wordPart{quoted: true, command: list})
+		default:
+			l.pos++
+			b.write(string(c), true)
+		}
+		empty = false
+	}
+	return &incompleteError{message: "syntax error: unterminated double quote"}
+}
+
+func isNameStart(c rune) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c rune) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+func isName(s string) bool {
+	for i, c := range s {
+		if !isNameChar(c) || (i == 0 && !isNameStart(c)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isSpecialParam(c rune) bool {
+	return strings.ContainsRune("?$!#@*-0123456789", c)
+}
+
+func (l *lexer) readDollar(b *wordBuilder, quoted bool) error {
+	l.pos++
+	if l.eof() {
+		b.write("$", quoted)
+		return nil
+	}
+
+	c := l.peek()
+	switch {
+	case c == '(':
+		l.pos++
+		list, err := l.readCommandSubst()
+		if err != nil {
+			return err
+		}
+		b.add(wordPart{quoted: quoted, command: list})
+	case c == '{':
+		l.pos++
+		content, err := l.readBraced()
+		if err != nil {
+			return err
+		}
+		param, err := parseParamExp(content)
+		if err != nil {
+			return err
+		}
+		b.add(wordPart{quoted: quoted, param: param})
+	case isNameStart(c):
+		start := l.pos
+		for !l.eof() && isNameChar(l.peek()) {
+			l.pos++
+		}
+		b.add(wordPart{quoted: quoted, param: &paramExp{name: string(l.input[start:l.pos])}})
+	case isSpecialParam(c):
+		l.pos++
+		b.add(wordPart{quoted: quoted, param: &paramExp{name: string(c)}})
+	default:
+		b.write("$", quoted)
+	}
+
+	return nil
+}
+
+func (l *lexer) readCommandSubst() (*commandList, error) {
+	heredocs, delimiter := l.heredocs, l.delimiter
+	l.heredocs, l.delimiter = nil, ""
+	defer func() {
+		l.heredocs, l.delimiter = heredocs, delimiter
+	}()
+
+	start := l.pos
+	depth := 0
+	cases := 0
+	// case and esac are only keywords in command position; the patterns
+	// of a case start after its "in".
+	command := true
+	caseIn := false
+
+	for {
+		l.skipBlanks()
+		end := l.pos
+
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+
+		atCommand := command
+		command = false
+		switch {
+		case tok.kind == tokenEOF:
+			return nil, &incompleteError{message: "syntax error: unexpected end of file while looking for matching `)'"}
+		case tok.kind == tokenNewline:
+			command = true
+		case tok.kind == tokenWord && atCommand && tok.text == "case":
+			cases++
+			caseIn = true
+		case tok.kind == tokenWord && atCommand && tok.text == "esac":
+			cases--
+		case tok.kind == tokenWord && caseIn && tok.text == "in":
+			caseIn = false
+			command = true
+		case tok.kind == tokenWord:
+			command = atCommand && slices.Contains(commandWords, tok.text)
+		case tok.kind == tokenOperator && tok.text == "(":
+			depth++
+			command = true
+		case tok.kind == tokenOperator && tok.text == ")":
+			if depth == 0 && cases == 0 {
+				return parseWithAliases(string(l.input[start:end]), l.aliases)
+			}
+			if depth > 0 {
+				depth--
+			}
+			command = true
+		case tok.kind == tokenOperator:
+			command = !strings.ContainsAny(tok.text, "<>")
+		}
+	}
+}
+
+// commandWords are the reserved words followed by a command.
+var commandWords = []string{"!", "{", "do", "elif", "else", "if", "then", "until", "while"}
+
+func (l *lexer) readBackquote(quoted bool) (*commandList, error) {
+	l.pos++
+
+	var sb strings.Builder
+	for !l.eof() {
+		c := l.peek()
+		l.pos++
+
+		switch c {
+		case '`':
+			return parseWithAliases(sb.String(), l.aliases)
+		case '\\':
+			if !l.eof() && (strings.ContainsRune("$`\\", l.peek()) || quoted && l.peek() == '"') {
+				c = l.peek()
+				l.pos++
+			}
+		}
+		sb.WriteRune(c)
+	}
+
+	return nil, &incompleteError{message: "syntax error: unterminated backquote"}
+}
+
+func lexHeredoc(body string) (*word, error) {
+	l := &lexer{input: []rune(body)}
+	b := &wordBuilder{w: &word{raw: body}}
+
+	for !l.eof() {
+		c := l.peek()
+		switch c {
+		case '\\':
+			l.pos++
+			if l.eof() || !strings.ContainsRune("$`\\\n", l.peek()) {
+				b.write("\\", true)
+				continue
+			}
+			escaped := l.peek()
+			l.pos++
+			if escaped != '\n' {
+				b.write(string(escaped), true)
+			}
+		case '$':
+			if err := l.readDollar(b, true); err != nil {
+				return nil, err
+			}
+		case '`':
+			list, err := l.readBackquote(true)
+			if err != nil {
+				return nil, err
+			}
+			b.add(wordPart{quoted: true, command: list})
+		default:
+			l.pos++
+			b.write(string(c), true)
+		}
+	}
+	b.flush()
+
+	return b.w, nil
+}
+
+func (l *lexer) readBraced() (string, error) {
+	start := l.pos
+	depth := 1
+
+	for !l.eof() {
+		c := l.peek()
+		l.pos++
+		switch c {
+		case '\\':
+			l.pos++
+		case '\'':
+			for !l.eof() && l.peek() != '\'' {
+				l.pos++
+			}
+			l.pos++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return string(l.input[start : l.pos-1]), nil
+			}
+		}
+	}
+
+	return "", &incompleteError{message: "syntax error: missing `}'"}
+}
+
+var paramOperators = []string{":-", ":=", ":+", ":?", "##", "%%", "-", "=", "+", "?", "#", "%"}
+
+func parseParamExp(content string) (*paramExp, error) {
+	badSubstitution := fmt.Errorf("${%s}: bad substitution", content)
+
+	if len(content) > 1 && content[0] == '#' {
+		name := content[1:]
+		base, _, _ := splitSubscript(name)
+		if isName(base) || isNumber(name) || (len(name) == 1 && isSpecialParam(rune(name[0]))) {
+			return &paramExp{name: name, op: "len"}, nil
+		}
+	}
+
+	var name string
+	switch {
+	case content == "":
+		return nil, badSubstitution
+	case isNameStart(rune(content[0])):
+		i := 1
+		for i < len(content) && isNameChar(rune(content[i])) {
+			i++
+		}
+		if end := strings.IndexByte(content[i:], ']'); end > 1 && content[i] == '[' {
+			i += end + 1
+		}
+		name = content[:i]
+	case content[0] >= '0' && content[0] <= '9':
+		i := 1
+		for i < len(content) && content[i] >= '0' && content[i] <= '9' {
+			i++
+		}
+		name = content[:i]
+	case isSpecialParam(rune(content[0])):
+		name = content[:1]
+	default:
+		return nil, badSubstitution
+	}
+
+	rest := content[len(name):]
+	if rest == "" {
+		return &paramExp{name: name}, nil
+	}
+
+	for _, op := range paramOperators {
+		if strings.HasPrefix(rest, op) {
+			arg, err := lexWord(rest[len(op):])
+			if err != nil {
+				return nil, err
+			}
+			return &paramExp{name: name, op: op, arg: arg}, nil
+		}
+	}
+
+	return nil, badSubstitution
+}
+
+// splitSubscript splits NAME[index] into its name and index.
+func splitSubscript(name string) (string, string, bool) {
+	base, index, ok := strings.Cut(name, "[")
+	if !ok || !strings.HasSuffix(index, "]") {
+		return name, "", false
+	}
+	return base, strings.TrimSuffix(index, "]"), true
+}
+
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"echo hello", []string{"echo", "hello"}, false},
+		{`echo "a  b"`, []string{"echo", "a  b"}, false},
+		{`echo 'a|b' c\ d`, []string{"echo", "a|b", "c d"}, false},
+		{`grep '|' file`, []string{"grep", "|", "file"}, false},
+		{"a|b&&c||d;e", []string{"a", "|", "b", "&&", "c", "||", "d", ";", "e"}, false},
+		{"echo x>out.txt", []string{"echo", "x", ">", "out.txt"}, false},
+		{"cmd 2>>err.log", []string{"cmd", "2", ">>", "err.log"}, false},
+		{"cmd 2>&1 3<&- >|f <>g &>>h", []string{"cmd", "2", ">&", "1", "3", "<&", "-", ">|", "f", "<>", "g", "&>>", "h"}, false},
+		{`echo "say \"hi\" \$HOME"`, []string{"echo", `say "hi" $HOME`}, false},
+		{`echo "a\nb"`, []string{"echo", `a\nb`}, false},
+		{`echo ""`, []string{"echo", ""}, false},
+		{"(cd /tmp)", []string{"(", "cd", "/tmp", ")"}, false},
+		{`echo "open`, nil, true},
+		{`echo 'open`, nil, true},
+	}
+
+	for _, test := range tests {
+		tokens, err := tokenize(test.input)
+		assert.Equal(t, test.wantErr, err != nil, test.input)
+		if err != nil {
+			continue
+		}
+
+		var res []string
+		for _, tok := range tokens {
+			switch tok.kind {
+			case tokenWord:
+				res = append(res, tok.word.String())
+			case tokenOperator, tokenIONumber:
+				res = append(res, tok.text)
+			}
+		}
+		assert.Equal(t, test.want, res, test.input)
+	}
+}
+
+func TestTokenizeIONumber(t *testing.T) {
+	tests := []struct {
+		input string
+		kinds []tokenKind
+	}{
+		{"cmd 2>err", []tokenKind{tokenWord, tokenIONumber, tokenOperator, tokenWord, tokenEOF}},
+		{"echo 2 >err", []tokenKind{tokenWord, tokenWord, tokenOperator, tokenWord, tokenEOF}},
+		{`echo "2">err`, []tokenKind{tokenWord, tokenWord, tokenOperator, tokenWord, tokenEOF}},
+		{"a\nb", []tokenKind{tokenWord, tokenNewline, tokenWord, tokenEOF}},
+	}
+
+	for _, test := range tests {
+		tokens, err := tokenize(test.input)
+		assert.NoError(t, err)
+
+		var kinds []tokenKind
+		for _, tok := range tokens {
+			kinds = append(kinds, tok.kind)
+		}
+		assert.Equal(t, test.kinds, kinds, test.input)
+	}
+}
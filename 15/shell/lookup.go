@@ -0,0 +1,152 @@
+package shell
+
+import (
+	"fmt"
+	"slices"
+)
+
+type commandKind int
+
+const (
+	kindNotFound commandKind = iota
+	kindAlias
+	kindKeyword
+	kindFunction
+	kindBuiltin
+	kindFile
+)
+
+func (k commandKind) String() string {
+	switch k {
+	case kindAlias:
+		return "alias"
+	case kindKeyword:
+		return "keyword"
+	case kindFunction:
+		return "function"
+	case kindBuiltin:
+		return "builtin"
+	case kindFile:
+		return "file"
+	}
+	return ""
+}
+
+// resolveCommand reports what a command name refers to, in the order the
+// shell looks it up. For files the path is returned as well.
+func (sh *shell) resolveCommand(name string) (commandKind, string) {
+	if _, ok := sh.aliases[name]; ok {
+		return kindAlias, ""
+	}
+	if slices.Contains(reservedWords, name) {
+		return kindKeyword, ""
+	}
+	if _, ok := sh.functions[name]; ok {
+		return kindFunction, ""
+	}
+	if _, ok := sh.lookupBuiltin(name); ok {
+		return kindBuiltin, ""
+	}
+	if path, err := sh.lookPath(name); err == nil {
+		return kindFile, path
+	}
+	return kindNotFound, ""
+}
+
+func (sh *shell) typeBuiltin(args []string, std *stdio) error {
+	short := len(args) > 0 && args[0] == "-t"
+	if short {
+		args = args[1:]
+	}
+
+	var err error
+	for _, name := range args {
+		kind, path := sh.resolveCommand(name)
+		if kind == kindNotFound {
+			if !short {
+				fmt.Fprintf(std.err(), "type: %s: not found\n", name)
+			}
+			err = &statusError{code: 1}
+			continue
+		}
+
+		if short {
+			fmt.Fprintln(std.out(), kind)
+			continue
+		}
+
+		switch kind {
+		case kindAlias:
+			fmt.Fprintf(std.out(), "%s is aliased to `%s'\n", name, sh.aliases[name])
+		case kindKeyword:
+			fmt.Fprintf(std.out(), "%s is a shell keyword\n", name)
+		case kindFunction:
+			fmt.Fprintf(std.out(), "%s is a function\n", name)
+		case kindBuiltin:
+			fmt.Fprintf(std.out(), "%s is a shell builtin\n", name)
+		case kindFile:
+			fmt.Fprintf(std.out(), "%s is %s\n", name, path)
+		}
+	}
+	return err
+}
+
+func (sh *shell) which(args []string, std *stdio) error {
+	var err error
+	for _, name := range args {
+		switch kind, path := sh.resolveCommand(name); kind {
+		case kindAlias:
+			fmt.Fprintf(std.out(), "%s: aliased to %s\n", name, sh.aliases[name])
+		case kindKeyword:
+			fmt.Fprintf(std.out(), "%s: shell reserved word\n", name)
+		case kindFunction:
+			fmt.Fprintf(std.out(), "%s: shell function\n", name)
+		case kindBuiltin:
+			fmt.Fprintf(std.out(), "%s: shell built-in command\n", name)
+		case kindFile:
+			fmt.Fprintln(std.out(), path)
+		default:
+			fmt.Fprintf(std.err(), "%s not found\n", name)
+			err = &statusError{code: 1}
+		}
+	}
+	return err
+}
+
+// command runs a builtin or external command, bypassing shell functions,
+// or with -v/-V describes how a name would be resolved.
+func (sh *shell) command(args []string, std *stdio) error {
+	if len(args) > 0 && (args[0] == "-v" || args[0] == "-V") {
+		if args[0] == "-V" {
+			return sh.typeBuiltin(args[1:], std)
+		}
+
+		var err error
+		for _, name := range args[1:] {
+			switch kind, path := sh.resolveCommand(name); kind {
+			case kindAlias:
+				fmt.Fprintf(std.out(), "alias %s=%s\n", name, shellQuote(sh.aliases[name]))
+			case kindFile:
+				fmt.Fprintln(std.out(), path)
+			case kindNotFound:
+				err = &statusError{code: 1}
+			default:
+				fmt.Fprintln(std.out(), name)
+			}
+		}
+		return err
+	}
+
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	if b, ok := sh.lookupBuiltin(args[0]); ok {
+		return sh.runBuiltin(b, args, std)
+	}
+
+	return sh.externalCommand(args, nil, std)
+}
@@ -0,0 +1,48 @@
+package shell
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandLookup(t *testing.T) {
+	dir := t.TempDir()
+	tool := filepath.Join(dir, "tool")
+	assert.NoError(t, os.WriteFile(tool, []byte("#!/bin/sh\necho external tool\n"), 0755))
+
+	prelude := "PATH=" + dir + "\nalias ll='ls -l'\nf() { echo fn; }\n"
+
+	tests := []struct {
+		script string
+		out    string
+		errOut string
+		code   int
+	}{
+		{"type ll cd if f tool", "ll is aliased to `ls -l'\ncd is a shell builtin\nif is a shell keyword\nf is a function\ntool is " + tool + "\n", "", 0},
+		{"type -t ll if f cd tool nope", "alias\nkeyword\nfunction\nbuiltin\nfile\n", "", 1},
+		{"type nope", "", "type: nope: not found\n", 1},
+		{"which ll f cd tool", "ll: aliased to ls -l\nf: shell function\ncd: shell built-in command\n" + tool + "\n", "", 0},
+		{"which nope", "", "nope not found\n", 1},
+		{"command -v ll f cd tool", "alias ll='ls -l'\nf\ncd\n" + tool + "\n", "", 0},
+		{"command -v nope", "", "", 1},
+		{"command -V cd", "cd is a shell builtin\n", "", 0},
+		{"tool() { echo shadowed; }\ncommand tool\ntool", "external tool\nshadowed\n", "", 0},
+		{"command echo builtin", "builtin\n", "", 0},
+		{"command nope", "", "nope: command not found\n", 127},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		sh := newTestShell(&out, &errOut)
+
+		err := sh.runInput(lineReader(strings.NewReader(prelude+test.script)), false)
+		assert.Equal(t, test.code, exitCode(err), test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Equal(t, test.errOut, errOut.String(), test.script)
+	}
+}
@@ -0,0 +1,67 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+var shellOptions = []struct {
+	name string
+	flag rune
+}{
+	{"errexit", 'e'},
+	{"failglob", 0},
+	{"noclobber", 'C'},
+	{"noglob", 'f'},
+	{"nounset", 'u'},
+	{"nullglob", 0},
+	{"pipefail", 0},
+	{"xtrace", 'x'},
+}
+
+func optionName(flag rune) string {
+	for _, option := range shellOptions {
+		if option.flag != 0 && option.flag == flag {
+			return option.name
+		}
+	}
+	return ""
+}
+
+func (sh *shell) setOption(name string, enable bool) error {
+	for _, option := range shellOptions {
+		if option.name == name {
+			if sh.options == nil {
+				sh.options = make(map[string]bool)
+			}
+			sh.options[name] = enable
+			return nil
+		}
+	}
+	return fmt.Errorf("set: %s: invalid option name", name)
+}
+
+func (sh *shell) listOptions(std *stdio, human bool) {
+	for _, option := range shellOptions {
+		switch {
+		case human && sh.options[option.name]:
+			fmt.Fprintf(std.out(), "%-15s\ton\n", option.name)
+		case human:
+			fmt.Fprintf(std.out(), "%-15s\toff\n", option.name)
+		case sh.options[option.name]:
+			fmt.Fprintf(std.out(), "set -o %s\n", option.name)
+		default:
+			fmt.Fprintf(std.out(), "set +o %s\n", option.name)
+		}
+	}
+}
+
+func (sh *shell) optionFlags() string {
+	var sb strings.Builder
+	for _, option := range shellOptions {
+		if option.flag != 0 && sh.options[option.name] {
+			sb.WriteRune(option.flag)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,592 @@
+package shell
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type parser struct {
+	input   []rune
+	tokens  []token
+	pos     int
+	aliases map[string]string
+}
+
+func parse(input string) (*commandList, error) {
+	return parseWithAliases(input, nil)
+}
+
+func parseWithAliases(input string, aliases map[string]string) (*commandList, error) {
+	tokens, err := tokenizeWithAliases(input, aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{input: []rune(input), tokens: tokens, aliases: aliases}
+
+	list, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, unexpectedToken(tok)
+	}
+
+	return list, nil
+}
+
+func unexpectedToken(tok token) error {
+	switch tok.kind {
+	case tokenEOF:
+		return &incompleteError{message: "syntax error: unexpected end of file"}
+	case tokenNewline:
+		return fmt.Errorf("syntax error near unexpected token `newline'")
+	default:
+		return fmt.Errorf("syntax error near unexpected token `%s'", tok.text)
+	}
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) advance() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) isOperator(ops ...string) bool {
+	tok := p.peek()
+	if tok.kind != tokenOperator {
+		return false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) textFrom(start int) string {
+	end := start
+	if p.pos > 0 {
+		end = p.tokens[p.pos-1].end
+	}
+	return string(p.input[start:max(start, end)])
+}
+
+func (p *parser) skipNewlines() {
+	for p.peek().kind == tokenNewline {
+		p.advance()
+	}
+}
+
+var reservedWords = []string{"!", "{", "}", "case", "do", "done", "elif", "else", "esac", "fi", "for", "function", "if", "in", "then", "until", "while"}
+
+var terminatorWords = []string{"then", "elif", "else", "fi", "do", "done", "esac", "}"}
+
+func (p *parser) isReserved(words ...string) bool {
+	tok := p.peek()
+	if tok.kind != tokenWord {
+		return false
+	}
+	text, ok := tok.word.literal()
+	if !ok {
+		return false
+	}
+	for _, w := range words {
+		if text == w {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expectReserved(word string) error {
+	if !p.isReserved(word) {
+		return unexpectedToken(p.peek())
+	}
+	p.advance()
+	return nil
+}
+
+func (p *parser) startsCommand() bool {
+	tok := p.peek()
+	switch tok.kind {
+	case tokenWord:
+		return !p.isReserved(terminatorWords...)
+	case tokenIONumber:
+		return true
+	case tokenOperator:
+		return tok.text == "(" || isRedirectOperator(tok.text)
+	}
+	return false
+}
+
+func (p *parser) parseList() (*commandList, error) {
+	list := &commandList{}
+
+	p.skipNewlines()
+	for p.startsCommand() {
+		start := p.peek().pos
+
+		cmds, err := p.parseConditional()
+		if err != nil {
+			return nil, err
+		}
+		item := &listItem{commands: cmds, text: p.textFrom(start)}
+		list.items = append(list.items, item)
+
+		if p.isOperator("&") {
+			p.advance()
+			item.background = true
+		} else if p.isOperator(";") {
+			p.advance()
+		} else if p.peek().kind != tokenNewline {
+			break
+		}
+		p.skipNewlines()
+	}
+
+	return list, nil
+}
+
+func (p *parser) parseConditional() ([]conditionalCommand, error) {
+	var cmds []conditionalCommand
+
+	for {
+		pl, err := p.parsePipeline()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.isOperator("&&", "||") {
+			cmds = append(cmds, conditionalCommand{pipeline: pl})
+			return cmds, nil
+		}
+
+		op := p.advance().text
+		cmds = append(cmds, conditionalCommand{pipeline: pl, operator: op})
+		p.skipNewlines()
+	}
+}
+
+func (p *parser) parsePipeline() (*pipeline, error) {
+	pl := &pipeline{}
+	start := p.peek().pos
+
+	if p.isReserved("!") {
+		p.advance()
+		pl.negate = true
+	}
+
+	for {
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		pl.commands = append(pl.commands, cmd)
+
+		if !p.isOperator("|") {
+			pl.text = p.textFrom(start)
+			return pl, nil
+		}
+		p.advance()
+		p.skipNewlines()
+	}
+}
+
+func (p *parser) parseCommand() (command, error) {
+	var cmd command
+	var redirs *[]*redirection
+
+	err := p.expandAlias()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.isOperator("("):
+		c := &subshell{}
+		c.body, err = p.parseSubshell()
+		cmd, redirs = c, &c.redirs
+	case p.isReserved("{"):
+		c := &braceGroup{}
+		c.body, err = p.parseBraceGroup()
+		cmd, redirs = c, &c.redirs
+	case p.isReserved("if"):
+		c := &ifClause{}
+		err = p.parseIf(c)
+		cmd, redirs = c, &c.redirs
+	case p.isReserved("while", "until"):
+		c := &loopClause{}
+		err = p.parseLoop(c)
+		cmd, redirs = c, &c.redirs
+	case p.isReserved("for"):
+		c := &forClause{}
+		err = p.parseFor(c)
+		cmd, redirs = c, &c.redirs
+	case p.isReserved("case"):
+		c := &caseClause{}
+		err = p.parseCase(c)
+		cmd, redirs = c, &c.redirs
+	case p.isReserved("function") || p.isFunctionDef():
+		return p.parseFunction()
+	default:
+		return p.parseSimpleCommand()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isRedirect() {
+		redir, err := p.parseRedirect()
+		if err != nil {
+			return nil, err
+		}
+		*redirs = append(*redirs, redir)
+	}
+
+	return cmd, nil
+}
+
+func (p *parser) parseCompoundList() (*commandList, error) {
+	list, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if len(list.items) == 0 {
+		return nil, unexpectedToken(p.peek())
+	}
+	return list, nil
+}
+
+func (p *parser) parseSubshell() (*commandList, error) {
+	p.advance()
+
+	body, err := p.parseCompoundList()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOperator(")") {
+		return nil, unexpectedToken(p.peek())
+	}
+	p.advance()
+
+	return body, nil
+}
+
+func (p *parser) parseBraceGroup() (*commandList, error) {
+	p.advance()
+
+	body, err := p.parseCompoundList()
+	if err != nil {
+		return nil, err
+	}
+	return body, p.expectReserved("}")
+}
+
+func (p *parser) parseIf(c *ifClause) error {
+	p.advance()
+
+	for {
+		condition, err := p.parseCompoundList()
+		if err != nil {
+			return err
+		}
+		if err := p.expectReserved("then"); err != nil {
+			return err
+		}
+		body, err := p.parseCompoundList()
+		if err != nil {
+			return err
+		}
+		c.branches = append(c.branches, ifBranch{condition: condition, body: body})
+
+		if !p.isReserved("elif") {
+			break
+		}
+		p.advance()
+	}
+
+	if p.isReserved("else") {
+		p.advance()
+		body, err := p.parseCompoundList()
+		if err != nil {
+			return err
+		}
+		c.elseBody = body
+	}
+
+	return p.expectReserved("fi")
+}
+
+func (p *parser) parseLoop(c *loopClause) error {
+	c.until = p.isReserved("until")
+	p.advance()
+
+	condition, err := p.parseCompoundList()
+	if err != nil {
+		return err
+	}
+	c.condition = condition
+
+	c.body, err = p.parseDoGroup()
+	return err
+}
+
+func (p *parser) parseDoGroup() (*commandList, error) {
+	if err := p.expectReserved("do"); err != nil {
+		return nil, err
+	}
+	body, err := p.parseCompoundList()
+	if err != nil {
+		return nil, err
+	}
+	return body, p.expectReserved("done")
+}
+
+func (p *parser) parseFor(c *forClause) error {
+	p.advance()
+
+	tok := p.peek()
+	name, ok := "", false
+	if tok.kind == tokenWord {
+		name, ok = tok.word.literal()
+	}
+	if !ok || !isName(name) {
+		return unexpectedToken(tok)
+	}
+	p.advance()
+	c.name = name
+
+	p.skipNewlines()
+	if p.isReserved("in") {
+		p.advance()
+		c.words = []*word{}
+		for p.peek().kind == tokenWord {
+			c.words = append(c.words, p.advance().word)
+		}
+		if !p.isOperator(";") && p.peek().kind != tokenNewline {
+			return unexpectedToken(p.peek())
+		}
+		p.advance()
+	} else if p.isOperator(";") {
+		p.advance()
+	}
+	p.skipNewlines()
+
+	body, err := p.parseDoGroup()
+	if err != nil {
+		return err
+	}
+	c.body = body
+
+	return nil
+}
+
+func (p *parser) parseCase(c *caseClause) error {
+	p.advance()
+
+	tok := p.peek()
+	if tok.kind != tokenWord {
+		return unexpectedToken(tok)
+	}
+	p.advance()
+	c.subject = tok.word
+
+	p.skipNewlines()
+	if err := p.expectReserved("in"); err != nil {
+		return err
+	}
+	p.skipNewlines()
+
+	for !p.isReserved("esac") {
+		item := &caseItem{}
+		if p.isOperator("(") {
+			p.advance()
+		}
+		for {
+			tok := p.peek()
+			if tok.kind != tokenWord {
+				return unexpectedToken(tok)
+			}
+			p.advance()
+			item.patterns = append(item.patterns, tok.word)
+
+			if !p.isOperator("|") {
+				break
+			}
+			p.advance()
+		}
+		if !p.isOperator(")") {
+			return unexpectedToken(p.peek())
+		}
+		p.advance()
+
+		body, err := p.parseList()
+		if err != nil {
+			return err
+		}
+		item.body = body
+		c.items = append(c.items, item)
+
+		if !p.isOperator(";;") {
+			break
+		}
+		p.advance()
+		p.skipNewlines()
+	}
+
+	return p.expectReserved("esac")
+}
+
+func (p *parser) isFunctionDef() bool {
+	if p.peek().kind != tokenWord || p.pos+2 >= len(p.tokens) {
+		return false
+	}
+	name, ok := p.peek().word.literal()
+	next, after := p.tokens[p.pos+1], p.tokens[p.pos+2]
+	return ok && isName(name) &&
+		next.kind == tokenOperator && next.text == "(" &&
+		after.kind == tokenOperator && after.text == ")"
+}
+
+func (p *parser) parseFunction() (command, error) {
+	if p.isReserved("function") {
+		p.advance()
+	}
+
+	tok := p.peek()
+	name, ok := "", false
+	if tok.kind == tokenWord {
+		name, ok = tok.word.literal()
+	}
+	if !ok || !isName(name) {
+		return nil, unexpectedToken(tok)
+	}
+	p.advance()
+
+	if p.isOperator("(") {
+		p.advance()
+		if !p.isOperator(")") {
+			return nil, unexpectedToken(p.peek())
+		}
+		p.advance()
+	}
+	p.skipNewlines()
+
+	if !p.isOperator("(") && !p.isReserved("{", "if", "while", "until", "for", "case") {
+		return nil, unexpectedToken(p.peek())
+	}
+
+	body, err := p.parseCommand()
+	if err != nil {
+		return nil, err
+	}
+
+	return &functionDef{name: name, body: body}, nil
+}
+
+func (p *parser) parseSimpleCommand() (command, error) {
+	cmd := &simpleCommand{}
+
+	for {
+		if p.isRedirect() {
+			redir, err := p.parseRedirect()
+			if err != nil {
+				return nil, err
+			}
+			cmd.redirs = append(cmd.redirs, redir)
+			continue
+		}
+
+		tok := p.peek()
+		if tok.kind != tokenWord {
+			break
+		}
+		p.advance()
+
+		if len(cmd.args) == 0 {
+			if assign := parseAssignment(tok.word); assign != nil {
+				cmd.assigns = append(cmd.assigns, assign)
+				if err := p.expandAlias(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		cmd.args = append(cmd.args, tok.word)
+	}
+
+	if len(cmd.args) == 0 && len(cmd.redirs) == 0 && len(cmd.assigns) == 0 {
+		return nil, unexpectedToken(p.peek())
+	}
+
+	return cmd, nil
+}
+
+func (p *parser) isRedirect() bool {
+	tok := p.peek()
+	return tok.kind == tokenIONumber || (tok.kind == tokenOperator && isRedirectOperator(tok.text))
+}
+
+func (p *parser) parseRedirect() (*redirection, error) {
+	redir := &redirection{fd: -1}
+
+	if p.peek().kind == tokenIONumber {
+		fd, err := strconv.Atoi(p.advance().text)
+		if err != nil {
+			return nil, err
+		}
+		redir.fd = fd
+	}
+
+	tok := p.advance()
+	if tok.kind != tokenOperator || !isRedirectOperator(tok.text) {
+		return nil, unexpectedToken(tok)
+	}
+	redir.op = tok.text
+
+	target := p.peek()
+	if target.kind != tokenWord {
+		return nil, unexpectedToken(target)
+	}
+	p.advance()
+	redir.target = target.word
+	redir.heredoc = target.heredoc
+
+	if redir.fd == -1 {
+		redir.fd = defaultFd(redir.op)
+	}
+
+	return redir, nil
+}
+
+func parseAssignment(w *word) *assignment {
+	first := w.parts[0]
+	if first.quoted || first.expands() {
+		return nil
+	}
+
+	name, value, ok := strings.Cut(first.text, "=")
+	if !ok || !isName(name) {
+		return nil
+	}
+
+	parts := append([]wordPart(nil), w.parts[1:]...)
+	if value != "" {
+		parts = append([]wordPart{{text: value}}, parts...)
+	}
+	if len(parts) == 0 {
+		parts = []wordPart{{quoted: true}}
+	}
+
+	return &assignment{name: name, value: &word{parts: parts}}
+}
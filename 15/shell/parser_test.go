@@ -0,0 +1,173 @@
+package shell
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input     string
+		items     int
+		operators []string
+		pipeline  int
+	}{
+		{"echo hello", 1, []string{""}, 1},
+		{"ls | wc -l", 1, []string{""}, 2},
+		{"true && echo ok || echo fail", 1, []string{"&&", "||", ""}, 1},
+		{"cd /tmp; pwd", 2, []string{""}, 1},
+		{"a | b | c && d", 1, []string{"&&", ""}, 3},
+		{"echo a\necho b\n", 2, []string{""}, 1},
+		{"echo $(echo case)", 1, []string{""}, 1},
+		{"echo $(echo esac) $(x)", 1, []string{""}, 1},
+		{"echo $(case $x in a) echo esac case;; esac) && echo in", 1, []string{"&&", ""}, 1},
+		{"echo $(if true; then case a in a) :;; esac; fi) | cat", 1, []string{""}, 2},
+	}
+
+	for _, test := range tests {
+		list, err := parse(test.input)
+		assert.NoError(t, err, test.input)
+		assert.Len(t, list.items, test.items, test.input)
+
+		var operators []string
+		for _, c := range list.items[0].commands {
+			operators = append(operators, c.operator)
+		}
+		assert.Equal(t, test.operators, operators, test.input)
+		assert.Len(t, list.items[0].commands[0].pipeline.commands, test.pipeline, test.input)
+	}
+}
+
+func TestParseRedirections(t *testing.T) {
+	list, err := parse("sort <in.txt >out.txt 2>>err.log extra &>all")
+	assert.NoError(t, err)
+
+	cmd := list.items[0].commands[0].pipeline.commands[0].(*simpleCommand)
+	var args []string
+	for _, arg := range cmd.args {
+		args = append(args, arg.String())
+	}
+	assert.Equal(t, []string{"sort", "extra"}, args)
+
+	var res []redirection
+	for _, redir := range cmd.redirs {
+		res = append(res, redirection{fd: redir.fd, op: redir.op, target: nil})
+	}
+	assert.Equal(t, []redirection{{fd: 0, op: "<"}, {fd: 1, op: ">"}, {fd: 2, op: ">>"}, {fd: 1, op: "&>"}}, res)
+	assert.Equal(t, "all", cmd.redirs[3].target.String())
+}
+
+func TestParseSubshell(t *testing.T) {
+	list, err := parse("(cd /tmp && pwd) > out.txt | cat")
+	assert.NoError(t, err)
+
+	commands := list.items[0].commands[0].pipeline.commands
+	assert.Len(t, commands, 2)
+
+	sub, ok := commands[0].(*subshell)
+	assert.True(t, ok)
+	assert.Len(t, sub.body.items[0].commands, 2)
+	assert.Len(t, sub.redirs, 1)
+}
+
+func TestParseNegation(t *testing.T) {
+	list, err := parse("! grep -q x file | cat && ! true")
+	assert.NoError(t, err)
+
+	cmds := list.items[0].commands
+	assert.True(t, cmds[0].pipeline.negate)
+	assert.Len(t, cmds[0].pipeline.commands, 2)
+	assert.True(t, cmds[1].pipeline.negate)
+
+	list, err = parse("echo !")
+	assert.NoError(t, err)
+	assert.False(t, list.items[0].commands[0].pipeline.negate)
+}
+
+func TestParseBackground(t *testing.T) {
+	list, err := parse("sleep 10 & echo a | cat && echo b; wait")
+	assert.NoError(t, err)
+	assert.Len(t, list.items, 3)
+
+	assert.True(t, list.items[0].background)
+	assert.Equal(t, "sleep 10", list.items[0].text)
+	assert.False(t, list.items[1].background)
+	assert.Equal(t, "echo a | cat && echo b", list.items[1].text)
+	assert.Equal(t, "echo a | cat", list.items[1].commands[0].pipeline.text)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"| ls",
+		"ls |",
+		"ls &&",
+		"echo >",
+		"(ls",
+		"()",
+		"ls )",
+		"a ;; b",
+		"& ls",
+		"ls & & ls",
+	}
+
+	for _, test := range tests {
+		_, err := parse(test)
+		assert.Error(t, err, test)
+	}
+}
+
+func TestParseIncomplete(t *testing.T) {
+	tests := []string{
+		"ls |",
+		"true &&",
+		"(ls",
+		"echo 'abc",
+		"echo \"abc",
+		"echo ${HOME",
+		"echo $(ls",
+		"echo `ls",
+		"cat <<EOF",
+		"cat <<EOF\nbody",
+	}
+
+	for _, test := range tests {
+		_, err := parse(test)
+		var incomplete *incompleteError
+		assert.True(t, errors.As(err, &incomplete), test)
+	}
+}
+
+func TestParseHeredoc(t *testing.T) {
+	tests := []struct {
+		input     string
+		op        string
+		delimiter string
+		body      string
+	}{
+		{"cat <<EOF\nhello\n  world\nEOF\necho after", "<<", "EOF", "hello\n  world\n"},
+		{"cat <<'EOF'\nhello $X\nEOF\n", "<<", "EOF", "hello $X\n"},
+		{"cat <<-END\n\t\tindented\n\tEND\n", "<<-", "END", "indented\n"},
+		{"cat <<\"E F\" | wc -l\nline\nE F\n", "<<", "E F", "line\n"},
+	}
+
+	for _, test := range tests {
+		list, err := parse(test.input)
+		assert.NoError(t, err, test.input)
+
+		cmd := list.items[0].commands[0].pipeline.commands[0].(*simpleCommand)
+		assert.Len(t, cmd.redirs, 1, test.input)
+		redir := cmd.redirs[0]
+		assert.Equal(t, test.op, redir.op, test.input)
+		assert.Equal(t, 0, redir.fd, test.input)
+		assert.Equal(t, test.delimiter, redir.heredoc.delimiter, test.input)
+		assert.Equal(t, test.body, redir.heredoc.body.String(), test.input)
+	}
+
+	list, err := parse("cat <<A; cat <<B\nfirst\nA\nsecond\nB\necho done")
+	assert.NoError(t, err)
+	assert.Len(t, list.items, 3)
+	assert.Equal(t, "first\n", list.items[0].commands[0].pipeline.commands[0].redirects()[0].heredoc.body.String())
+	assert.Equal(t, "second\n", list.items[1].commands[0].pipeline.commands[0].redirects()[0].heredoc.body.String())
+}
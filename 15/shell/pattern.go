@@ -0,0 +1,217 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func escapePattern(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func hasPatternChars(pattern string) bool {
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*' || r == '?' || r == '[':
+			return true
+		}
+	}
+	return false
+}
+
+func unescapePattern(pattern string) string {
+	var sb strings.Builder
+	escaped := false
+	for _, r := range pattern {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func matchPattern(pattern, s string) bool {
+	return matchRunes([]rune(pattern), []rune(s))
+}
+
+func matchRunes(pattern, s []rune) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchRunes(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			pattern = pattern[1:]
+			s = s[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			matched, rest, ok := matchClass(pattern, s[0])
+			if !ok {
+				if s[0] != '[' {
+					return false
+				}
+				pattern = pattern[1:]
+				s = s[1:]
+				continue
+			}
+			if !matched {
+				return false
+			}
+			pattern = rest
+			s = s[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+			pattern = pattern[1:]
+			s = s[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+func matchClass(pattern []rune, c rune) (bool, []rune, bool) {
+	i := 1
+	negate := false
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		negate = true
+		i++
+	}
+
+	matched := false
+	first := true
+	for i < len(pattern) {
+		if pattern[i] == ']' && !first {
+			return matched != negate, pattern[i+1:], true
+		}
+		first = false
+
+		lo := pattern[i]
+		if lo == '\\' && i+1 < len(pattern) {
+			i++
+			lo = pattern[i]
+		}
+		i++
+
+		hi := lo
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			hi = pattern[i+1]
+			if hi == '\\' && i+2 < len(pattern) {
+				i++
+				hi = pattern[i+1]
+			}
+			i += 2
+		}
+
+		if lo <= c && c <= hi {
+			matched = true
+		}
+	}
+
+	return false, nil, false
+}
+
+// glob expands pattern into the matching paths. Relative patterns are
+// matched in dir and expand to relative paths.
+func glob(dir, pattern string) []string {
+	prefixes := []string{""}
+	if strings.HasPrefix(pattern, "/") {
+		prefixes = []string{"/"}
+	}
+
+	components := strings.Split(strings.TrimLeft(pattern, "/"), "/")
+	for i, component := range components {
+		var next []string
+		for _, prefix := range prefixes {
+			lookup := prefix
+			if lookup == "" {
+				lookup = "."
+			}
+			for _, name := range globComponent(resolvePath(dir, lookup), component) {
+				if i < len(components)-1 {
+					name += "/"
+				}
+				next = append(next, prefix+name)
+			}
+		}
+		prefixes = next
+	}
+
+	var res []string
+	for _, path := range prefixes {
+		if _, err := os.Lstat(resolvePath(dir, path)); err == nil {
+			res = append(res, path)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func globComponent(dir, component string) []string {
+	if !hasPatternChars(component) {
+		return []string{unescapePattern(component)}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var res []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(component, ".") {
+			continue
+		}
+		if matchPattern(component, name) {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+// resolvePath resolves a relative name against dir, where an empty dir is
+// the current directory of the process.
+func resolvePath(dir, name string) string {
+	if dir == "" || name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
@@ -0,0 +1,144 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+func (sh *shell) executeCommand(cmd command, std *stdio) error {
+	switch c := cmd.(type) {
+	case *simpleCommand:
+		return sh.identCommand(c, std)
+	case *subshell:
+		return sh.executeSubshell(c, std)
+	case *braceGroup:
+		return sh.executeBraceGroup(c, std)
+	case *ifClause:
+		return sh.executeIf(c, std)
+	case *loopClause:
+		return sh.executeLoop(c, std)
+	case *forClause:
+		return sh.executeFor(c, std)
+	case *caseClause:
+		return sh.executeCase(c, std)
+	case *functionDef:
+		return sh.defineFunction(c)
+	default:
+		return fmt.Errorf("unsupported command %T", cmd)
+	}
+}
+
+func (sh *shell) executePipeline(p *pipeline, std *stdio) error {
+	if len(p.commands) == 0 {
+		return errors.New("no commands in pipeline")
+	}
+
+	if len(p.commands) == 1 {
+		err := sh.executeCommand(p.commands[0], std)
+		sh.setPipeStatus([]error{err})
+		return err
+	}
+
+	j := sh.job
+	foreground := j == nil
+	if foreground {
+		j = newJob(p.text)
+		j.foreground = true
+	}
+
+	errs := make([]error, len(p.commands))
+	var wg sync.WaitGroup
+	var in *os.File
+	var pipeErr error
+
+	for i, c := range p.commands {
+		cmdStd := std.clone()
+		var files []*os.File
+		if in != nil {
+			cmdStd.set(0, in)
+			files = append(files, in)
+			in = nil
+		}
+
+		if i < len(p.commands)-1 {
+			reader, writer, err := os.Pipe()
+			if err != nil {
+				pipeErr = err
+				closePipes(files)
+				break
+			}
+			cmdStd.set(1, writer)
+			files = append(files, writer)
+			in = reader
+		}
+
+		sub := sh.subshell()
+		sub.job = j
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := sub.executeCommand(c, cmdStd)
+			if isControlFlow(err) {
+				err = statusFromCode(exitCode(err))
+			}
+			errs[i] = reportError(err, cmdStd)
+			closePipes(files)
+		}()
+	}
+
+	wait := func() error {
+		wg.Wait()
+		if pipeErr != nil {
+			return pipeErr
+		}
+		return sh.pipelineStatus(errs)
+	}
+
+	if !foreground {
+		err := wait()
+		sh.setPipeStatus(errs)
+		return err
+	}
+
+	go func() {
+		j.finish(wait())
+	}()
+
+	err := sh.waitForeground(j)
+	select {
+	case <-j.done:
+		sh.setPipeStatus(errs)
+	default:
+	}
+	return err
+}
+
+func (sh *shell) pipelineStatus(errs []error) error {
+	if sh.options["pipefail"] {
+		for i := len(errs) - 1; i >= 0; i-- {
+			if errs[i] != nil {
+				return errs[i]
+			}
+		}
+	}
+	return errs[len(errs)-1]
+}
+
+func (sh *shell) setPipeStatus(errs []error) {
+	codes := make([]string, len(errs))
+	for i, err := range errs {
+		codes[i] = strconv.Itoa(exitCode(err))
+	}
+	sh.setVar("PIPESTATUS", strings.Join(codes, " "))
+}
+
+func closePipes(files []*os.File) {
+	for _, file := range files {
+		_ = file.Close()
+	}
+}
@@ -0,0 +1,45 @@
+package shell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipelines(t *testing.T) {
+	tests := []struct {
+		script string
+		out    string
+		code   int
+	}{
+		{"echo hi | cat", "hi\n", 0},
+		{"echo a b | tr ' ' '\\n' | wc -l | tr -d ' '", "2\n", 0},
+		{"pwd | cat >/dev/null; echo $?", "0\n", 0},
+		{"f() { echo func; }; f | tr a-z A-Z", "FUNC\n", 0},
+		{"for i in 1 2 3; do echo $i; done | tail -1", "3\n", 0},
+		{"{ echo b; echo a; } | sort", "a\nb\n", 0},
+		{"x=1; x=2 | cat; echo $x", "1\n", 0},
+		{"yes | head -2", "y\ny\n", 0},
+		{"echo | exit 3; echo $?", "3\n", 0},
+		{"true | false; echo $? ${PIPESTATUS[0]} ${PIPESTATUS[1]} ${#PIPESTATUS[@]}", "1 0 1 2\n", 0},
+		{"false | true; echo $? \"${PIPESTATUS[@]}\"", "0 1 0\n", 0},
+		{"false; echo $PIPESTATUS", "1\n", 0},
+		{"echo x | grep y | cat; echo $?", "0\n", 0},
+		{"set -o pipefail; false | true; echo $?; true | true; echo $?", "1\n0\n", 0},
+		{"! true; echo $?; ! false; echo $?", "1\n0\n", 0},
+		{"! echo x | grep y; echo $? ${PIPESTATUS[1]}", "0 1\n", 0},
+		{"set -e; ! true; echo survived", "survived\n", 0},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		sh := newTestShell(&out, &errOut)
+
+		err := sh.runInput(lineReader(strings.NewReader(test.script)), false)
+		assert.Equal(t, test.code, exitCode(err), test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Empty(t, errOut.String(), test.script)
+	}
+}
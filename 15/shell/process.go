@@ -0,0 +1,491 @@
+package shell
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// clockTicks is USER_HZ, the unit of the time fields in /proc/<pid>/stat.
+// It is fixed at 100 in the Linux user-space ABI.
+const clockTicks = 100
+
+type process struct {
+	pid     int
+	ppid    int
+	uid     int
+	user    string
+	state   byte
+	tty     string
+	ttyNr   int
+	cpuTime float64
+	start   float64
+	cpu     float64
+	rss     int
+	comm    string
+	cmd     string
+}
+
+type psColumn struct {
+	name    string
+	header  string
+	numeric bool
+	value   func(p *process) string
+}
+
+var psColumns = []psColumn{
+	{"pid", "PID", true, func(p *process) string { return strconv.Itoa(p.pid) }},
+	{"ppid", "PPID", true, func(p *process) string { return strconv.Itoa(p.ppid) }},
+	{"uid", "UID", true, func(p *process) string { return strconv.Itoa(p.uid) }},
+	{"user", "USER", false, func(p *process) string { return p.user }},
+	{"state", "S", false, func(p *process) string { return string(p.state) }},
+	{"cpu", "%CPU", true, func(p *process) string { return strconv.FormatFloat(p.cpu, 'f', 1, 64) }},
+	{"rss", "RSS", true, func(p *process) string { return strconv.Itoa(p.rss) }},
+	{"tty", "TTY", false, func(p *process) string { return p.tty }},
+	{"time", "TIME", false, func(p *process) string { return formatCPUTime(p.cpuTime) }},
+	{"comm", "COMMAND", false, func(p *process) string { return p.comm }},
+	{"cmd", "CMD", false, func(p *process) string { return p.cmd }},
+}
+
+var psAliases = map[string]string{
+	"s": "state", "stat": "state", "%cpu": "cpu", "pcpu": "cpu",
+	"tname": "tty", "ucomm": "comm", "args": "cmd", "command": "cmd",
+}
+
+const (
+	psDefaultFormat = "pid,tty,time,comm"
+	psFullFormat    = "user,pid,ppid,state,cpu,rss,tty,time,cmd"
+)
+
+func (sh *shell) ps(args []string, std *stdio) error {
+	all := false
+	full := false
+	var format []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' {
+			return fmt.Errorf("ps: %s: unsupported argument", arg)
+		}
+
+		for j := 1; j < len(arg); j++ {
+			switch arg[j] {
+			case 'e', 'A':
+				all = true
+			case 'f':
+				full = true
+			case 'o':
+				spec := arg[j+1:]
+				if spec == "" {
+					i++
+					if i == len(args) {
+						return errors.New("ps: -o: format specification required")
+					}
+					spec = args[i]
+				}
+				format = append(format, spec)
+				j = len(arg)
+			default:
+				return fmt.Errorf("ps: -%c: invalid option", arg[j])
+			}
+		}
+	}
+
+	if len(format) == 0 {
+		format = []string{psDefaultFormat}
+		if full {
+			format = []string{psFullFormat}
+		}
+	}
+
+	columns, err := parsePsFormat(strings.Join(format, ","))
+	if err != nil {
+		return err
+	}
+
+	procs, err := readProcesses()
+	if err != nil {
+		return fmt.Errorf("ps: %v", err)
+	}
+
+	if !all {
+		self, err := readProcess(os.Getpid())
+		if err != nil {
+			return fmt.Errorf("ps: %v", err)
+		}
+		procs = selectProcesses(procs, self)
+	}
+	setUserNames(procs)
+
+	if uptime, err := systemUptime(); err == nil {
+		for _, p := range procs {
+			if elapsed := uptime - p.start; elapsed > 0 {
+				p.cpu = p.cpuTime / elapsed * 100
+			}
+		}
+	}
+
+	return writeProcesses(std.out(), procs, columns)
+}
+
+func parsePsFormat(format string) ([]psColumn, error) {
+	var res []psColumn
+
+	for _, spec := range strings.Split(format, ",") {
+		name, header, custom := strings.Cut(spec, "=")
+		name = strings.ToLower(name)
+		if alias, ok := psAliases[name]; ok {
+			name = alias
+		}
+
+		found := false
+		for _, column := range psColumns {
+			if column.name == name {
+				if custom {
+					column.header = header
+				}
+				res = append(res, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("ps: %s: unknown format specifier", spec)
+		}
+	}
+
+	return res, nil
+}
+
+// selectProcesses keeps the processes a bare ps shows: those of the same
+// user attached to the same terminal as the shell.
+func selectProcesses(procs []*process, self *process) []*process {
+	var res []*process
+	for _, p := range procs {
+		if p.uid == self.uid && p.ttyNr == self.ttyNr {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+func writeProcesses(w io.Writer, procs []*process, columns []psColumn) error {
+	rows := make([][]string, 0, len(procs)+1)
+
+	header := make([]string, len(columns))
+	showHeader := false
+	for i, column := range columns {
+		header[i] = column.header
+		showHeader = showHeader || column.header != ""
+	}
+	if showHeader {
+		rows = append(rows, header)
+	}
+
+	for _, p := range procs {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = column.value(p)
+		}
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(columns))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	for _, row := range rows {
+		var cells []string
+		for i, cell := range row {
+			switch {
+			case columns[i].numeric:
+				cell = fmt.Sprintf("%*s", widths[i], cell)
+			case i < len(row)-1:
+				cell = fmt.Sprintf("%-*s", widths[i], cell)
+			}
+			cells = append(cells, cell)
+		}
+		fmt.Fprintln(out, strings.Join(cells, " "))
+	}
+	return out.Flush()
+}
+
+func readProcesses() ([]*process, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		p, err := readProcess(pid)
+		if err != nil {
+			continue
+		}
+		res = append(res, p)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].pid < res[j].pid
+	})
+	return res, nil
+}
+
+func readProcess(pid int) (*process, error) {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	p, err := parseStat(string(stat))
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if sys, ok := info.Sys().(*syscall.Stat_t); ok {
+		p.uid = int(sys.Uid)
+	}
+	p.cmd = "[" + p.comm + "]"
+	cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+	if err == nil && len(cmdline) > 0 {
+		p.cmd = strings.Join(strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00"), " ")
+	}
+
+	return p, nil
+}
+
+// parseStat parses the fields of /proc/<pid>/stat that ps displays. The
+// command name is enclosed in parentheses and may itself contain spaces.
+func parseStat(stat string) (*process, error) {
+	open := strings.IndexByte(stat, '(')
+	end := strings.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return nil, errors.New("malformed stat")
+	}
+
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 22 {
+		return nil, errors.New("malformed stat")
+	}
+	field := func(n int) int {
+		value, _ := strconv.Atoi(fields[n-3])
+		return value
+	}
+
+	p := &process{
+		comm:  stat[open+1 : end],
+		state: fields[0][0],
+		ppid:  field(4),
+		ttyNr: field(7),
+		rss:   field(24) * os.Getpagesize() / 1024,
+	}
+	p.pid, _ = strconv.Atoi(strings.TrimSpace(stat[:open]))
+	p.tty = ttyName(p.ttyNr)
+	p.cpuTime = float64(field(14)+field(15)) / clockTicks
+	p.start = float64(field(22)) / clockTicks
+
+	return p, nil
+}
+
+func ttyName(nr int) string {
+	major := (nr >> 8) & 0xfff
+	minor := (nr & 0xff) | ((nr >> 12) & 0xfff00)
+
+	switch {
+	case nr == 0:
+		return "?"
+	case major == 4:
+		return "tty" + strconv.Itoa(minor)
+	case major >= 136 && major <= 143:
+		return "pts/" + strconv.Itoa(minor+(major-136)*256)
+	}
+	return "?"
+}
+
+func systemUptime() (float64, error) {
+	data, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, errors.New("malformed uptime")
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+func setUserNames(procs []*process) {
+	names := make(map[int]string)
+	for _, p := range procs {
+		name, ok := names[p.uid]
+		if !ok {
+			name = strconv.Itoa(p.uid)
+			if u, err := user.LookupId(name); err == nil {
+				name = u.Username
+			}
+			names[p.uid] = name
+		}
+		p.user = name
+	}
+}
+
+func formatCPUTime(seconds float64) string {
+	total := int(seconds)
+	days := total / 86400
+	res := fmt.Sprintf("%02d:%02d:%02d", total/3600%24, total/60%60, total%60)
+	if days > 0 {
+		res = strconv.Itoa(days) + "-" + res
+	}
+	return res
+}
+
+func (sh *shell) kill(args []string, std *stdio) error {
+	sig := syscall.SIGTERM
+
+	if len(args) > 0 && args[0] == "-l" {
+		return listSignals(args[1:], std)
+	}
+
+	if len(args) > 0 && (args[0] == "-s" || args[0] == "-n") {
+		if len(args) < 2 {
+			return fmt.Errorf("kill: %s: option requires an argument", args[0])
+		}
+		var err error
+		sig, err = parseSignal(args[1])
+		if err != nil {
+			return err
+		}
+		args = args[2:]
+	} else if len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-' && args[0] != "--" {
+		var err error
+		sig, err = parseSignal(args[0][1:])
+		if err != nil {
+			return err
+		}
+		args = args[1:]
+	}
+
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return errors.New("kill: usage: kill [-s sigspec | -n signum | -sigspec] pid | jobspec ... or kill -l [sigspec]")
+	}
+
+	var res error
+	for _, arg := range args {
+		err := sh.signalTarget(arg, sig)
+		if err != nil {
+			fmt.Fprintln(std.err(), err)
+			res = &statusError{code: 1}
+		}
+	}
+	return res
+}
+
+func (sh *shell) signalTarget(target string, sig syscall.Signal) error {
+	if strings.HasPrefix(target, "%") {
+		j, err := sh.jobs.find(target)
+		if err != nil {
+			return fmt.Errorf("kill: %v", err)
+		}
+		err = j.signal(sig)
+		if err != nil {
+			return fmt.Errorf("kill: %s: %v", target, err)
+		}
+		if j.currentState() == jobStopped && (sig == syscall.SIGTERM || sig == syscall.SIGHUP) {
+			_ = j.signal(syscall.SIGCONT)
+		}
+		return nil
+	}
+
+	pid, err := strconv.Atoi(target)
+	if err != nil {
+		return fmt.Errorf("kill: %s: arguments must be process or job IDs", target)
+	}
+
+	err = syscall.Kill(pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return fmt.Errorf("kill: (%d) - No such process", pid)
+	}
+	if err != nil {
+		return fmt.Errorf("kill: (%d) - %v", pid, err)
+	}
+	return nil
+}
+
+func parseSignal(spec string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(spec); err == nil {
+		if unix.SignalName(syscall.Signal(n)) == "" && n != 0 {
+			return 0, fmt.Errorf("kill: %s: invalid signal specification", spec)
+		}
+		return syscall.Signal(n), nil
+	}
+
+	name := strings.ToUpper(spec)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig := unix.SignalNum(name)
+	if sig == 0 {
+		return 0, fmt.Errorf("kill: %s: invalid signal specification", spec)
+	}
+	return sig, nil
+}
+
+func listSignals(args []string, std *stdio) error {
+	if len(args) == 0 {
+		var names []string
+		for sig := syscall.Signal(1); sig < 32; sig++ {
+			if name := unix.SignalName(sig); name != "" {
+				names = append(names, fmt.Sprintf("%2d) %s", int(sig), name))
+			}
+		}
+		_, err := fmt.Fprintln(std.out(), strings.Join(names, "\n"))
+		return err
+	}
+
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil {
+			if n > 128 {
+				n -= 128
+			}
+			name := unix.SignalName(syscall.Signal(n))
+			if name == "" {
+				return fmt.Errorf("kill: %s: invalid signal specification", arg)
+			}
+			fmt.Fprintln(std.out(), strings.TrimPrefix(name, "SIG"))
+			continue
+		}
+
+		sig, err := parseSignal(arg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(std.out(), int(sig))
+	}
+	return nil
+}
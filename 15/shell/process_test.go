@@ -0,0 +1,127 @@
+package shell
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStat(t *testing.T) {
+	stat := "4242 (my (odd) cmd) S 1 4242 4242 34816 4242 4194304 100 0 0 0 250 50 0 0 20 0 1 0 1000 10000000 25 18446744073709551615"
+
+	p, err := parseStat(stat)
+	assert.NoError(t, err)
+	assert.Equal(t, 4242, p.pid)
+	assert.Equal(t, 1, p.ppid)
+	assert.Equal(t, "my (odd) cmd", p.comm)
+	assert.Equal(t, byte('S'), p.state)
+	assert.Equal(t, "pts/0", p.tty)
+	assert.Equal(t, 3.0, p.cpuTime)
+	assert.Equal(t, 10.0, p.start)
+	assert.Equal(t, 25*os.Getpagesize()/1024, p.rss)
+
+	_, err = parseStat("4242 (truncated) S 1")
+	assert.Error(t, err)
+}
+
+func TestWriteProcesses(t *testing.T) {
+	procs := []*process{
+		{pid: 1, ppid: 0, user: "root", state: 'S', cmd: "/sbin/init", tty: "?"},
+		{pid: 12345, ppid: 1, user: "alice", state: 'R', cmd: "sleep 10", tty: "pts/1", cpuTime: 3725},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"pid,user,state,cmd", "  PID USER  S CMD\n    1 root  S /sbin/init\n12345 alice R sleep 10\n"},
+		{"pid=,args=", "    1 /sbin/init\n12345 sleep 10\n"},
+		{"pid,time=CPU", "  PID CPU\n    1 00:00:00\n12345 01:02:05\n"},
+	}
+
+	for _, test := range tests {
+		columns, err := parsePsFormat(test.format)
+		assert.NoError(t, err, test.format)
+
+		var out bytes.Buffer
+		assert.NoError(t, writeProcesses(&out, procs, columns), test.format)
+		assert.Equal(t, test.want, out.String(), test.format)
+	}
+
+	_, err := parsePsFormat("pid,bogus")
+	assert.EqualError(t, err, "ps: bogus: unknown format specifier")
+}
+
+func TestPs(t *testing.T) {
+	var out, errOut bytes.Buffer
+	sh := newTestShell(&out, &errOut)
+
+	err := sh.runInput(lineReader(strings.NewReader("ps -e -o pid=,ppid=")), false)
+	assert.NoError(t, err)
+	want := []string{strconv.Itoa(os.Getpid()), strconv.Itoa(os.Getppid())}
+	found := false
+	for _, line := range strings.Split(out.String(), "\n") {
+		found = found || slices.Equal(strings.Fields(line), want)
+	}
+	assert.True(t, found, "ps lists the shell: %q", out.String())
+
+	out.Reset()
+	err = sh.runInput(lineReader(strings.NewReader("ps -x")), false)
+	assert.Equal(t, 1, exitCode(err))
+	assert.Equal(t, "ps: -x: invalid option\n", errOut.String())
+}
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    syscall.Signal
+		wantErr bool
+	}{
+		{"9", syscall.SIGKILL, false},
+		{"KILL", syscall.SIGKILL, false},
+		{"SIGKILL", syscall.SIGKILL, false},
+		{"hup", syscall.SIGHUP, false},
+		{"0", 0, false},
+		{"FOO", 0, true},
+		{"999", 0, true},
+	}
+
+	for _, test := range tests {
+		sig, err := parseSignal(test.spec)
+		assert.Equal(t, test.wantErr, err != nil, test.spec)
+		assert.Equal(t, test.want, sig, test.spec)
+	}
+}
+
+func TestKill(t *testing.T) {
+	tests := []struct {
+		script string
+		out    string
+		errOut string
+		code   int
+	}{
+		{"kill -l 9 137 TERM", "KILL\nKILL\n15\n", "", 0},
+		{"sleep 10 & kill -9 $!; wait $!; echo $?", "137\n", "", 0},
+		{"sleep 10 & sleep 10 & kill -s INT %1 %2; wait; echo done", "done\n", "", 0},
+		{"kill -0 $$ 999999999", "", "kill: (999999999) - No such process\n", 1},
+		{"kill %9", "", "kill: no such job\n", 1},
+		{"kill -BOGUS 1", "", "kill: BOGUS: invalid signal specification\n", 1},
+		{"kill abc", "", "kill: abc: arguments must be process or job IDs\n", 1},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		sh := newTestShell(&out, &errOut)
+
+		err := sh.runInput(lineReader(strings.NewReader(test.script)), false)
+		assert.Equal(t, test.code, exitCode(err), test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Equal(t, test.errOut, errOut.String(), test.script)
+	}
+}
@@ -0,0 +1,137 @@
+package shell
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPS1 = `\u@\h:\w\$ `
+	defaultPS2 = "> "
+)
+
+// Text between \[ and \] in a prompt takes no width on the terminal, as
+// with colour escapes. expandPrompt marks it the way readline does.
+const (
+	promptIgnoreStart = '\x01'
+	promptIgnoreEnd   = '\x02'
+)
+
+func (sh *shell) prompt() string {
+	ps1, ok := sh.lookupVar("PS1")
+	if !ok {
+		ps1 = defaultPS1
+	}
+	return sh.expandPrompt(ps1)
+}
+
+func (sh *shell) continuationPrompt() string {
+	ps2, ok := sh.lookupVar("PS2")
+	if !ok {
+		ps2 = defaultPS2
+	}
+	return sh.expandPrompt(ps2)
+}
+
+func (sh *shell) expandPrompt(ps1 string) string {
+	var sb strings.Builder
+
+	runes := []rune(ps1)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '\\' || i == len(runes)-1 {
+			sb.WriteRune(runes[i])
+			continue
+		}
+
+		i++
+		switch runes[i] {
+		case 'u':
+			sb.WriteString(sh.currentUser())
+		case 'h':
+			host, _ := os.Hostname()
+			host, _, _ = strings.Cut(host, ".")
+			sb.WriteString(host)
+		case 'H':
+			host, _ := os.Hostname()
+			sb.WriteString(host)
+		case 'w':
+			sb.WriteString(promptDir(sh.workDir(), sh.homeDir(), false))
+		case 'W':
+			sb.WriteString(promptDir(sh.workDir(), sh.homeDir(), true))
+		case '$':
+			if os.Geteuid() == 0 {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('$')
+			}
+		case '?':
+			sb.WriteString(strconv.Itoa(sh.status))
+		case 't':
+			sb.WriteString(time.Now().Format("15:04:05"))
+		case 'n':
+			sb.WriteByte('\n')
+		case 'e':
+			sb.WriteByte(0x1b)
+		case '\\':
+			sb.WriteByte('\\')
+		case '[':
+			sb.WriteRune(promptIgnoreStart)
+		case ']':
+			sb.WriteRune(promptIgnoreEnd)
+		default:
+			sb.WriteRune('\\')
+			sb.WriteRune(runes[i])
+		}
+	}
+
+	return sb.String()
+}
+
+// visiblePrompt strips the \[ \] markers from an expanded prompt and
+// returns it with the width its last line takes on the terminal.
+func visiblePrompt(prompt string) (string, int) {
+	var sb strings.Builder
+	width := 0
+	ignore := false
+	for _, r := range prompt {
+		switch {
+		case r == promptIgnoreStart:
+			ignore = true
+			continue
+		case r == promptIgnoreEnd:
+			ignore = false
+			continue
+		case r == '\n':
+			width = 0
+		case !ignore:
+			width++
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String(), width
+}
+
+func (sh *shell) currentUser() string {
+	if name := sh.getVar("USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return strconv.Itoa(os.Getuid())
+}
+
+func promptDir(dir, home string, base bool) string {
+	if home != "" && (dir == home || strings.HasPrefix(dir, home+"/")) {
+		dir = "~" + strings.TrimPrefix(dir, home)
+	}
+
+	if base && dir != "/" && dir != "~" {
+		return filepath.Base(dir)
+	}
+	return dir
+}
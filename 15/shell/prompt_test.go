@@ -0,0 +1,65 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandPrompt(t *testing.T) {
+	home := t.TempDir()
+	dir := filepath.Join(home, "project")
+	assert.NoError(t, os.Mkdir(dir, 0755))
+
+	t.Chdir(dir)
+
+	sh := &shell{vars: environVars([]string{"USER=alice", "HOME=" + home})}
+
+	tests := []struct {
+		ps1    string
+		status int
+		want   string
+	}{
+		{"$ ", 0, "$ "},
+		{`\u> `, 0, "alice> "},
+		{`[\?] `, 127, "[127] "},
+		{`\w`, 0, "~/project"},
+		{`\W`, 0, "project"},
+		{`a\nb`, 0, "a\nb"},
+		{`\[\e[1m\]x`, 0, "\x01\x1b[1m\x02x"},
+		{`\\ \q`, 0, `\ \q`},
+	}
+
+	for _, test := range tests {
+		sh.status = test.status
+		assert.Equal(t, test.want, sh.expandPrompt(test.ps1), test.ps1)
+	}
+
+	t.Chdir(home)
+	assert.Equal(t, "~", sh.expandPrompt(`\w`))
+
+	other := t.TempDir()
+	t.Chdir(other)
+	assert.Equal(t, other, sh.expandPrompt(`\w`))
+}
+
+func TestVisiblePrompt(t *testing.T) {
+	tests := []struct {
+		prompt string
+		want   string
+		width  int
+	}{
+		{"$ ", "$ ", 2},
+		{"\x01\x1b[1m\x02$\x01\x1b[0m\x02 ", "\x1b[1m$\x1b[0m ", 2},
+		{"\x1b[1m$ ", "\x1b[1m$ ", 6},
+		{"first\n\x01\x1b[32m\x02> ", "first\n\x1b[32m> ", 2},
+	}
+
+	for _, test := range tests {
+		got, width := visiblePrompt(test.prompt)
+		assert.Equal(t, test.want, got, test.prompt)
+		assert.Equal(t, test.width, width, test.prompt)
+	}
+}
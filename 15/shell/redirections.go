@@ -0,0 +1,259 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// stdio is a table of open file descriptors. Each entry is an io.Reader,
+// an io.Writer or both (an *os.File); a missing entry is a closed descriptor.
+type stdio struct {
+	fds map[int]any
+}
+
+func newStdio(in io.Reader, out, err io.Writer) *stdio {
+	lockedOut, lockedErr := lockWriter(out), lockWriter(err)
+	if out == err {
+		lockedErr = lockedOut
+	}
+	return &stdio{fds: map[int]any{0: in, 1: lockedOut, 2: lockedErr}}
+}
+
+func (s *stdio) clone() *stdio {
+	fds := make(map[int]any, len(s.fds))
+	for fd, f := range s.fds {
+		fds[fd] = f
+	}
+	return &stdio{fds: fds}
+}
+
+func (s *stdio) set(fd int, f any) {
+	s.fds[fd] = f
+}
+
+func (s *stdio) close(fd int) {
+	delete(s.fds, fd)
+}
+
+func (s *stdio) reader(fd int) io.Reader {
+	if r, ok := s.fds[fd].(io.Reader); ok {
+		return r
+	}
+	return badFd(fd)
+}
+
+func (s *stdio) writer(fd int) io.Writer {
+	if w, ok := s.fds[fd].(io.Writer); ok {
+		return w
+	}
+	return badFd(fd)
+}
+
+func (s *stdio) in() io.Reader  { return s.reader(0) }
+func (s *stdio) out() io.Writer { return s.writer(1) }
+func (s *stdio) err() io.Writer { return s.writer(2) }
+
+// attach passes the table to an external command. Closed descriptors stay
+// closed in the command; descriptors above 2 are inherited only if they are
+// files.
+func (s *stdio) attach(cmd *exec.Cmd) {
+	cmd.Stdin, _ = s.fds[0].(io.Reader)
+	cmd.Stdout, _ = s.fds[1].(io.Writer)
+	cmd.Stderr, _ = s.fds[2].(io.Writer)
+
+	// exec.Cmd opens /dev/null for a nil stream, but passes a nil *os.File
+	// on as it is, and the child closes the descriptor of a nil file.
+	var closed *os.File
+	if _, ok := s.fds[0]; !ok {
+		cmd.Stdin = closed
+	}
+	if _, ok := s.fds[1]; !ok {
+		cmd.Stdout = closed
+	}
+	if _, ok := s.fds[2]; !ok {
+		cmd.Stderr = closed
+	}
+
+	cmd.ExtraFiles = nil
+	for fd, f := range s.fds {
+		file, ok := f.(*os.File)
+		if fd < 3 || !ok {
+			continue
+		}
+		for len(cmd.ExtraFiles) <= fd-3 {
+			cmd.ExtraFiles = append(cmd.ExtraFiles, nil)
+		}
+		cmd.ExtraFiles[fd-3] = file
+	}
+}
+
+// lockWriter guards an in-memory writer with a mutex, so that pipeline
+// stages and background jobs can share it. Files are returned as is.
+func lockWriter(w io.Writer) io.Writer {
+	switch w.(type) {
+	case *os.File, *lockedWriter:
+		return w
+	}
+	return &lockedWriter{w: w}
+}
+
+type lockedWriter struct {
+	w     io.Writer
+	mutex sync.Mutex
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mutex.Lock()
+	defer lw.mutex.Unlock()
+	return lw.w.Write(p)
+}
+
+type badFd int
+
+func (fd badFd) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("%d: bad file descriptor", int(fd))
+}
+
+func (fd badFd) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("%d: bad file descriptor", int(fd))
+}
+
+type redirection struct {
+	fd      int
+	op      string
+	target  *word
+	heredoc *heredoc
+}
+
+func defaultFd(op string) int {
+	if strings.HasPrefix(op, "<") {
+		return 0
+	}
+	return 1
+}
+
+func (sh *shell) applyRedirection(redirs []*redirection, std *stdio) (*stdio, func(), error) {
+	res := std.clone()
+	var files []*os.File
+
+	closeFiles := func() {
+		for _, file := range files {
+			err := file.Close()
+			if err != nil {
+				fmt.Fprintln(std.err(), err)
+			}
+		}
+	}
+
+	for _, redir := range redirs {
+		file, err := sh.redirect(redir, res)
+		if err != nil {
+			closeFiles()
+			return nil, nil, err
+		}
+		if file != nil {
+			files = append(files, file)
+		}
+	}
+
+	return res, closeFiles, nil
+}
+
+func (sh *shell) redirect(redir *redirection, std *stdio) (*os.File, error) {
+	switch redir.op {
+	case "<<", "<<-":
+		body, err := sh.expandString(redir.heredoc.body)
+		if err != nil {
+			return nil, err
+		}
+		std.set(redir.fd, strings.NewReader(body))
+		return nil, nil
+	case "<<<":
+		value, err := sh.expandString(&word{parts: sh.expandTilde(redir.target.parts, false)})
+		if err != nil {
+			return nil, err
+		}
+		std.set(redir.fd, strings.NewReader(value+"\n"))
+		return nil, nil
+	}
+
+	fields, err := sh.expandWord(redir.target)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("%s: ambiguous redirect", redir.target.raw)
+	}
+	name := fields[0]
+
+	op := redir.op
+	if op == ">&" || op == "<&" {
+		if name == "-" {
+			std.close(redir.fd)
+			return nil, nil
+		}
+		src, err := strconv.Atoi(name)
+		if err != nil {
+			if op == "<&" || redir.fd != 1 {
+				return nil, fmt.Errorf("%s: ambiguous redirect", redir.target.raw)
+			}
+			op = "&>"
+		} else {
+			f, ok := std.fds[src]
+			if !ok {
+				return nil, fmt.Errorf("%d: bad file descriptor", src)
+			}
+			std.set(redir.fd, f)
+			return nil, nil
+		}
+	}
+
+	file, err := sh.openRedirect(op, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if op == "&>" || op == "&>>" {
+		std.set(1, file)
+		std.set(2, file)
+	} else {
+		std.set(redir.fd, file)
+	}
+	return file, nil
+}
+
+func (sh *shell) openRedirect(op, name string) (*os.File, error) {
+	switch op {
+	case "<":
+		return sh.openFile(name, os.O_RDONLY, 0)
+	case "<>":
+		return sh.openFile(name, os.O_CREATE|os.O_RDWR, 0644)
+	case ">>", "&>>":
+		return sh.openFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	case ">|":
+		return sh.openFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	}
+
+	if sh.options["noclobber"] {
+		info, err := os.Stat(sh.path(name))
+		if err == nil && info.Mode().IsRegular() {
+			return nil, fmt.Errorf("%s: cannot overwrite existing file", name)
+		}
+	}
+	return sh.openFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+}
+
+func isRedirectOperator(arg string) bool {
+	operators := []string{">", ">>", ">|", "&>", "&>>", ">&", "<", "<>", "<&", "<<", "<<-", "<<<"}
+	for _, operator := range operators {
+		if arg == operator {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package shell
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyRedirectionHeredoc(t *testing.T) {
+	sh := &shell{
+		jobs: &jobTable{},
+		std:  newStdio(os.Stdin, io.Discard, io.Discard),
+		vars: environVars([]string{"NAME=world", "HOME=/home/alice"}),
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"cat <<EOF\nhello $NAME\n$(echo sub) \\$NAME `echo tick`\nEOF\n", "hello world\nsub $NAME tick\n"},
+		{"cat <<'EOF'\nhello $NAME\nEOF\n", "hello $NAME\n"},
+		{"cat <<-EOF\n\thello ${NAME}\n\tEOF\n", "hello world\n"},
+		{"cat <<< \"$NAME  x\"", "world  x\n"},
+		{"cat <<< ~/file", "/home/alice/file\n"},
+	}
+
+	for _, test := range tests {
+		list, err := parse(test.input)
+		assert.NoError(t, err, test.input)
+
+		cmd := list.items[0].commands[0].pipeline.commands[0].(*simpleCommand)
+		std, closeFiles, err := sh.applyRedirection(cmd.redirs, sh.std)
+		assert.NoError(t, err, test.input)
+
+		content, err := io.ReadAll(std.in())
+		assert.NoError(t, err, test.input)
+		assert.Equal(t, test.want, string(content), test.input)
+		closeFiles()
+	}
+}
+
+func TestRedirections(t *testing.T) {
+	tests := []struct {
+		script string
+		out    string
+		errOut string
+		code   int
+	}{
+		{"echo a >f; cat f", "a\n", "", 0},
+		{"echo a>f; echo b>>f; cat <f", "a\nb\n", "", 0},
+		{"echo err >&2", "", "err\n", 0},
+		{"{ echo out; echo err >&2; } 2>&1 >f; cat f", "err\nout\n", "", 0},
+		{"{ echo out; echo err >&2; } >f 2>&1; cat f", "out\nerr\n", "", 0},
+		{"echo x 3>f >&3; cat f", "x\n", "", 0},
+		{"echo x 3>f 1>&3 3>&-; cat f", "x\n", "", 0},
+		{"cat nonexistent 2>f; test -s f && echo logged", "logged\n", "", 0},
+		{"echo x >&4", "", "4: bad file descriptor\n", 1},
+		{"echo x >&-", "", "1: bad file descriptor\n", 1},
+		{"echo old >f; set -C; echo new >f; cat f", "old\n", "f: cannot overwrite existing file\n", 0},
+		{"echo old >f; set -o noclobber; echo new >|f; cat f", "new\n", "", 0},
+		{"echo data >f; cat <>f; cat 0<>g; test -e g && echo created", "data\ncreated\n", "", 0},
+		{"echo a &>f; echo b &>>f; cat f", "a\nb\n", "", 0},
+		{"echo both >&f; cat f", "both\n", "", 0},
+		{"cat 3<<EOF <&3\nfrom three\nEOF", "from three\n", "", 0},
+		{"sh -c 'echo inherited >&3' 3>f; cat f", "inherited\n", "", 0},
+		{"sh -c 'test -e /proc/$$/fd/0 && echo open || echo closed' <&-", "closed\n", "", 0},
+		{"sh -c 'echo x 2>/dev/null || echo failed >&2' >&-", "", "failed\n", 0},
+		{"echo >f; sh -c 'test -e /proc/$$/fd/3 && echo open || echo closed' 3<f 3<&-", "closed\n", "", 0},
+	}
+
+	for _, test := range tests {
+		t.Chdir(t.TempDir())
+
+		var out, errOut bytes.Buffer
+		sh := newTestShell(&out, &errOut)
+
+		err := sh.runInput(lineReader(strings.NewReader(test.script)), false)
+		assert.Equal(t, test.code, exitCode(err), test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Equal(t, test.errOut, errOut.String(), test.script)
+	}
+}
@@ -0,0 +1,156 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Builtin is a command that runs inside the shell process. Help returns a
+// synopsis line, optionally followed by a blank line and a description.
+type Builtin interface {
+	Name() string
+	Help() string
+	Run(inv *Invocation) error
+}
+
+// Invocation is a single run of a builtin. Args[0] is the command name, Env
+// holds the exported variables in "NAME=value" form and Dir is the working
+// directory of the shell.
+type Invocation struct {
+	Args   []string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Env    []string
+	Dir    string
+
+	shell *shell
+	std   *stdio
+}
+
+// Open opens a file for reading relative to Dir.
+func (inv *Invocation) Open(name string) (*os.File, error) {
+	return inv.shell.openFile(name, os.O_RDONLY, 0)
+}
+
+type registeredBuiltin struct {
+	Builtin
+	fallback bool
+}
+
+var (
+	builtins      = make(map[string]registeredBuiltin)
+	builtinsMutex sync.RWMutex
+)
+
+// RegisterBuiltin adds b to the builtins of all interpreters, replacing any
+// builtin with the same name.
+func RegisterBuiltin(b Builtin) {
+	builtinsMutex.Lock()
+	defer builtinsMutex.Unlock()
+	builtins[b.Name()] = registeredBuiltin{Builtin: b}
+}
+
+// registerFallbackBuiltin adds a builtin that is only used when no
+// executable of the same name is found in PATH, or through `builtin`.
+func registerFallbackBuiltin(b Builtin) {
+	builtinsMutex.Lock()
+	defer builtinsMutex.Unlock()
+	builtins[b.Name()] = registeredBuiltin{Builtin: b, fallback: true}
+}
+
+func findBuiltin(name string) (registeredBuiltin, bool) {
+	builtinsMutex.RLock()
+	defer builtinsMutex.RUnlock()
+	b, ok := builtins[name]
+	return b, ok
+}
+
+func builtinNames() []string {
+	builtinsMutex.RLock()
+	defer builtinsMutex.RUnlock()
+
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (sh *shell) lookupBuiltin(name string) (Builtin, bool) {
+	b, ok := findBuiltin(name)
+	if !ok {
+		return nil, false
+	}
+	if b.fallback {
+		if _, err := sh.lookPath(name); err == nil {
+			return nil, false
+		}
+	}
+	return b.Builtin, true
+}
+
+func (sh *shell) runBuiltin(b Builtin, args []string, std *stdio) error {
+	return b.Run(&Invocation{
+		Args:   args,
+		Stdin:  std.in(),
+		Stdout: std.out(),
+		Stderr: std.err(),
+		Env:    sh.environ(),
+		Dir:    sh.workDir(),
+		shell:  sh,
+		std:    std,
+	})
+}
+
+// shellBuiltin adapts the builtins that need access to the shell state.
+type shellBuiltin struct {
+	name string
+	help string
+	run  func(sh *shell, args []string, std *stdio) error
+}
+
+func (b *shellBuiltin) Name() string { return b.name }
+func (b *shellBuiltin) Help() string { return b.help }
+
+func (b *shellBuiltin) Run(inv *Invocation) error {
+	return b.run(inv.shell, inv.Args, inv.std)
+}
+
+func (sh *shell) help(args []string, std *stdio) error {
+	if len(args) == 0 {
+		for _, name := range builtinNames() {
+			b, _ := findBuiltin(name)
+			synopsis, _, _ := strings.Cut(b.Help(), "\n")
+			fmt.Fprintf(std.out(), "%-10s %s\n", name, synopsis)
+		}
+		return nil
+	}
+
+	for _, name := range args {
+		b, ok := findBuiltin(name)
+		if !ok {
+			return fmt.Errorf("help: no help topics match `%s'", name)
+		}
+		fmt.Fprintln(std.out(), b.Help())
+	}
+	return nil
+}
+
+// builtin runs a builtin even when a function, or for fallback builtins an
+// executable, has the same name.
+func (sh *shell) builtin(args []string, std *stdio) error {
+	if len(args) == 0 {
+		return nil
+	}
+	b, ok := findBuiltin(args[0])
+	if !ok {
+		return fmt.Errorf("builtin: %s: not a shell builtin", args[0])
+	}
+	return sh.runBuiltin(b.Builtin, args, std)
+}
@@ -0,0 +1,63 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type greetBuiltin struct{}
+
+func (greetBuiltin) Name() string { return "greet" }
+func (greetBuiltin) Help() string { return "greet [name ...]\n\nGreet everyone read from stdin." }
+
+func (greetBuiltin) Run(inv *Invocation) error {
+	lines, err := readInputLines(inv, inv.Args[1:])
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		fmt.Fprintf(inv.Stdout, "hello %s\n", line)
+	}
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	RegisterBuiltin(greetBuiltin{})
+	defer func() {
+		builtinsMutex.Lock()
+		delete(builtins, "greet")
+		builtinsMutex.Unlock()
+	}()
+
+	prelude := "PATH=/nonexistent\n"
+
+	tests := []struct {
+		script string
+		out    string
+		errOut string
+		code   int
+	}{
+		{"echo world | greet", "hello world\n", "", 0},
+		{"echo a b | greet | greet", "hello hello a b\n", "", 0},
+		{"type greet sort", "greet is a shell builtin\nsort is a shell builtin\n", "", 0},
+		{"help greet", "greet [name ...]\n\nGreet everyone read from stdin.\n", "", 0},
+		{"help | grep -F 'greet      greet [name ...]'", "greet      greet [name ...]\n", "", 0},
+		{"help nope", "", "help: no help topics match `nope'\n", 1},
+		{"echo() { :; }\nbuiltin echo hi", "hi\n", "", 0},
+		{"builtin nope", "", "builtin: nope: not a shell builtin\n", 1},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		sh := newTestShell(&out, &errOut)
+
+		err := sh.runInput(lineReader(strings.NewReader(prelude+test.script)), false)
+		assert.Equal(t, test.code, exitCode(err), test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Equal(t, test.errOut, errOut.String(), test.script)
+	}
+}
@@ -0,0 +1,229 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type shellExit struct {
+	code int
+}
+
+func (e *shellExit) Error() string {
+	return fmt.Sprintf("exit %d", e.code)
+}
+
+func lineReader(r io.Reader) func(string) (string, error) {
+	reader := bufio.NewReader(r)
+	return func(string) (string, error) {
+		input, err := reader.ReadString('\n')
+		if err != nil && input == "" {
+			return "", err
+		}
+		return strings.TrimSuffix(input, "\n"), nil
+	}
+}
+
+func (sh *shell) runInput(readLine func(prompt string) (string, error), interactive bool) error {
+	for {
+		if interactive {
+			sh.jobs.notify(sh.std.err())
+		}
+
+		input, err := readLine(sh.prompt())
+		if errors.Is(err, errInterrupted) {
+			sh.status = 130
+			continue
+		}
+		if err != nil {
+			return statusFromCode(sh.status)
+		}
+
+		if strings.TrimSpace(input) == "" {
+			continue
+		}
+
+		list, err := parseWithAliases(input, sh.aliases)
+		var incomplete *incompleteError
+		for errors.As(err, &incomplete) {
+			more, readErr := readLine(sh.continuationPrompt())
+			if readErr != nil {
+				if errors.Is(readErr, errInterrupted) {
+					err = readErr
+				}
+				break
+			}
+			input += "\n" + more
+			list, err = parseWithAliases(input, sh.aliases)
+		}
+
+		if interactive && sh.history != nil {
+			sh.history.add(input)
+		}
+
+		if errors.Is(err, errInterrupted) {
+			sh.status = 130
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(sh.std.err(), "%s: %s\n", sh.name, err)
+			sh.status = 2
+			if !interactive {
+				return &statusError{code: 2}
+			}
+			continue
+		}
+
+		err = sh.executeList(list, sh.std)
+		sh.status = exitCode(err)
+
+		var exit *shellExit
+		var ret *functionReturn
+		if errors.As(err, &exit) || errors.As(err, &ret) {
+			return err
+		}
+	}
+}
+
+func (sh *shell) runScript(path string, args []string) error {
+	file, err := sh.openFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		fmt.Fprintf(sh.std.err(), "%s: %s\n", sh.name, err)
+		return &statusError{code: 127}
+	}
+	defer file.Close()
+
+	sh.name = path
+	sh.args = args
+
+	return sh.runInput(lineReader(file), false)
+}
+
+// isShellScript reports whether path is an executable text file without a
+// #! line, which the kernel refuses to run and shells run themselves.
+func isShellScript(path string) bool {
+	if !isExecutable(path) {
+		return false
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	head := make([]byte, 80)
+	n, _ := io.ReadFull(file, head)
+	head = head[:n]
+	if bytes.HasPrefix(head, []byte("#!")) || bytes.HasPrefix(head, []byte("\x7fELF")) {
+		return false
+	}
+	line, _, _ := bytes.Cut(head, []byte("\n"))
+	return bytes.IndexByte(line, 0) < 0
+}
+
+// runShellScript runs a script as a separate shell would: it sees only the
+// exported variables, not the functions, aliases or options of this one.
+func (sh *shell) runShellScript(path string, args, env []string, std *stdio) error {
+	script := newShell(&Config{Env: env, Dir: sh.dir})
+	script.ctx = sh.ctx
+	script.std = std
+	script.job = sh.job
+
+	err := script.runExitTrap(script.runScript(path, args))
+	return statusFromCode(exitCode(err))
+}
+
+func (sh *shell) source(args []string) error {
+	if len(args) == 0 {
+		return &statusError{code: 2}
+	}
+
+	file, err := sh.openFile(args[0], os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if len(args) > 1 {
+		saved := sh.args
+		sh.args = args[1:]
+		defer func() {
+			sh.args = saved
+		}()
+	}
+
+	sh.sourceDepth++
+	defer func() {
+		sh.sourceDepth--
+	}()
+
+	err = sh.runInput(lineReader(file), false)
+
+	var ret *functionReturn
+	if errors.As(err, &ret) {
+		return statusFromCode(ret.code)
+	}
+	return err
+}
+
+func (sh *shell) exit(args []string, std *stdio) error {
+	code := sh.status
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Fprintf(std.err(), "exit: %s: numeric argument required\n", args[0])
+			return &shellExit{code: 2}
+		}
+		code = n & 0xff
+	}
+	return &shellExit{code: code}
+}
+
+func (sh *shell) shift(args []string) error {
+	n := 1
+	if len(args) > 0 {
+		var err error
+		n, err = strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return fmt.Errorf("shift: %s: numeric argument required", args[0])
+		}
+	}
+	if n > len(sh.args) {
+		return errors.New("shift: shift count out of range")
+	}
+	sh.args = sh.args[n:]
+	return nil
+}
+
+func (sh *shell) trace(assigns []*assignment, values map[string]string, args []string, std *stdio) {
+	if !sh.options["xtrace"] {
+		return
+	}
+
+	ps4, ok := sh.lookupVar("PS4")
+	if !ok {
+		ps4 = "+ "
+	}
+
+	words := make([]string, 0, len(assigns)+len(args))
+	for _, assign := range assigns {
+		words = append(words, assign.name+"="+shellQuote(values[assign.name]))
+	}
+	for _, arg := range args {
+		words = append(words, shellQuote(arg))
+	}
+	fmt.Fprintf(std.err(), "%s%s\n", ps4, strings.Join(words, " "))
+}
+
+func statusFromCode(code int) error {
+	if code == 0 {
+		return nil
+	}
+	return &statusError{code: code}
+}
@@ -0,0 +1,88 @@
+package shell
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestShell(out, errOut *bytes.Buffer) *shell {
+	return &shell{
+		jobs: &jobTable{},
+		std:  newStdio(strings.NewReader(""), out, errOut),
+		vars: environVars(os.Environ()),
+		name: "l2sh",
+	}
+}
+
+func TestRunInput(t *testing.T) {
+	tests := []struct {
+		script string
+		args   []string
+		out    string
+		errOut string
+		code   int
+	}{
+		{"# comment\necho a # trailing\necho b#c", nil, "a\nb#c\n", "", 0},
+		{"echo one \\\n  two", nil, "one two\n", "", 0},
+		{"echo $# $1 \"$2\"\nshift\necho $# $1", []string{"x", "y z"}, "2 x y z\n1 y z\n", "", 0},
+		{"set -- a b c\necho $# $*\nset d\necho $@", nil, "3 a b c\nd\n", "", 0},
+		{"echo start\nexit 3\necho unreachable", nil, "start\n", "", 3},
+		{"false\nexit", nil, "", "", 1},
+		{"exit x 2>/dev/null", nil, "", "", 2},
+		{"exit x 2>&1", nil, "exit: x: numeric argument required\n", "", 2},
+		{"(exit 4); echo $?", nil, "4\n", "", 0},
+		{"echo $(exit 5) $?", nil, "5\n", "", 0},
+		{"set -e\nfalse || echo handled\nfalse && echo no\necho alive\nfalse\necho dead", nil, "handled\nalive\n", "", 1},
+		{"set -u\necho ${UNSET_VAR:-default}\necho $UNSET_VAR\necho dead", nil, "default\n", "UNSET_VAR: unbound variable\n", 1},
+		{"set -x\necho \"a b\" c\nset +x\necho d", nil, "a b c\nd\n", "+ echo 'a b' c\n+ set +x\n", 0},
+		{"echo $-; set -ex; echo $-", nil, "\nex\n", "+ echo ex\n", 0},
+		{"echo ok\necho (\necho after", nil, "ok\n", "l2sh: syntax error near unexpected token `('\n", 2},
+		{"cat <<EOF\nline $1\nEOF", []string{"arg"}, "line arg\n", "", 0},
+		{"shift 2", []string{"x"}, "", "shift: shift count out of range\n", 1},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		sh := newTestShell(&out, &errOut)
+		sh.args = test.args
+
+		err := sh.runInput(lineReader(strings.NewReader(test.script)), false)
+		assert.Equal(t, test.code, exitCode(err), test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Equal(t, test.errOut, errOut.String(), test.script)
+	}
+}
+
+func TestSource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "lib.sh")
+	assert.NoError(t, os.WriteFile(path, []byte("GREETING=\"hello $1\"\necho sourced $#\n"), 0644))
+
+	var out, errOut bytes.Buffer
+	sh := newTestShell(&out, &errOut)
+	sh.args = []string{"a", "b", "c"}
+
+	err := sh.runInput(lineReader(strings.NewReader(". "+path+" world\necho $GREETING $#\nsource "+path)), false)
+	assert.NoError(t, err)
+	assert.Equal(t, "sourced 1\nhello world 3\nsourced 3\n", out.String())
+	assert.Empty(t, errOut.String())
+}
+
+func TestScriptFile(t *testing.T) {
+	path, err := filepath.Abs("test_script.sh")
+	assert.NoError(t, err)
+	t.Chdir(t.TempDir())
+
+	var out, errOut bytes.Buffer
+	sh := newTestShell(&out, &errOut)
+
+	err = sh.runScript(path, nil)
+	assert.NoError(t, err, out.String()+errOut.String())
+	assert.Contains(t, out.String(), "=== Tests completed ===")
+	assert.Equal(t, path, sh.name)
+}
@@ -0,0 +1,231 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+type shell struct {
+	ctx         context.Context
+	jobs        *jobTable
+	job         *job
+	std         *stdio
+	history     *history
+	vars        map[string]*variable
+	options     map[string]bool
+	functions   map[string]*functionDef
+	aliases     map[string]string
+	traps       map[syscall.Signal]string
+	signals     chan os.Signal
+	ignored     []os.Signal
+	interrupts  chan os.Signal
+	interrupted *atomic.Bool
+	hash        map[string]hashEntry
+	hashPath    string
+	locals      []map[string]*variable
+	args        []string
+	name        string
+	dir         string
+	interactive bool
+	ttyFd       int
+	pgid        int
+	status      int
+	lastBgPid   int
+	substStatus int
+
+	loopDepth      int
+	conditionDepth int
+	sourceDepth    int
+	forwardSignals bool
+}
+
+func newShell(config *Config) *shell {
+	stdin, stdout, stderr := config.Stdin, config.Stdout, config.Stderr
+	if stdin == nil {
+		stdin = strings.NewReader("")
+	}
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	name := config.Name
+	if name == "" {
+		name = "l2sh"
+	}
+
+	return &shell{
+		ctx:  context.Background(),
+		jobs: &jobTable{},
+		std:  newStdio(stdin, stdout, stderr),
+		vars: environVars(config.Env),
+		args: append([]string(nil), config.Args...),
+		name: name,
+		dir:  config.Dir,
+	}
+}
+
+func (sh *shell) subshell() *shell {
+	sub := *sh
+	sub.vars = cloneVars(sh.vars)
+	sub.options = maps.Clone(sh.options)
+	sub.functions = maps.Clone(sh.functions)
+	sub.aliases = maps.Clone(sh.aliases)
+	sub.hash = maps.Clone(sh.hash)
+	sub.locals = make([]map[string]*variable, len(sh.locals))
+	for i, frame := range sh.locals {
+		sub.locals[i] = maps.Clone(frame)
+	}
+	sub.args = append([]string(nil), sh.args...)
+
+	// Subshells keep ignored signals but reset the other traps.
+	sub.traps = nil
+	sub.signals = nil
+	for sig, action := range sh.traps {
+		if action == "" {
+			if sub.traps == nil {
+				sub.traps = make(map[syscall.Signal]string)
+			}
+			sub.traps[sig] = action
+		}
+	}
+	return &sub
+}
+
+// workDir returns the working directory of the shell. An empty dir means
+// the directory of the process, as for exec.Cmd.
+func (sh *shell) workDir() string {
+	if sh.dir != "" {
+		return sh.dir
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return dir
+}
+
+// path resolves a relative name against the working directory of the shell.
+func (sh *shell) path(name string) string {
+	return resolvePath(sh.dir, name)
+}
+
+// openFile opens a file relative to the working directory of the shell and
+// reports errors with the name as given.
+func (sh *shell) openFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	file, err := os.OpenFile(sh.path(name), flag, perm)
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		pathErr.Path = name
+	}
+	return file, err
+}
+
+// canceled reports whether the context of the shell is done. The error
+// unwinds the running commands like exit does.
+func (sh *shell) canceled() error {
+	if sh.ctx == nil || sh.ctx.Err() == nil {
+		return nil
+	}
+	return &shellExit{code: 130}
+}
+
+// Main runs the shell with the command line arguments of the process and
+// returns its exit status.
+func Main(args []string) int {
+	dir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	sh := newShell(&Config{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Env:    os.Environ(),
+		Dir:    dir,
+		Name:   args[0],
+	})
+
+	args = args[1:]
+	command := false
+	for len(args) > 0 && len(args[0]) > 1 && (args[0][0] == '-' || args[0][0] == '+') {
+		arg := args[0]
+		args = args[1:]
+		if arg == "--" {
+			break
+		}
+		if arg == "-c" {
+			command = true
+			continue
+		}
+
+		options := []string{arg}
+		if (arg == "-o" || arg == "+o") && len(args) > 0 {
+			options = append(options, args[0])
+			args = args[1:]
+		}
+		if err := sh.set(options, sh.std); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", sh.name, err)
+			return 2
+		}
+	}
+
+	sh.forwardSignals = !inForeground()
+
+	if command || len(args) > 0 {
+		sh.catchInterrupts()
+	}
+
+	switch {
+	case command:
+		if len(args) == 0 {
+			fmt.Fprintf(os.Stderr, "%s: -c: option requires an argument\n", sh.name)
+			return 2
+		}
+		if len(args) > 1 {
+			sh.name = args[1]
+			sh.args = args[2:]
+		}
+		return exitCode(sh.runExitTrap(sh.runInput(lineReader(strings.NewReader(args[0])), false)))
+	case len(args) > 0:
+		return exitCode(sh.runExitTrap(sh.runScript(args[0], args[1:])))
+	}
+
+	sh.initJobControl()
+	sh.catchInterrupts()
+	if sh.interactive {
+		sh.forwardSignals = false
+	}
+
+	sh.dir, err = os.UserHomeDir()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if sh.interactive {
+		var exit *shellExit
+		if err := sh.loadRC(); errors.As(err, &exit) {
+			return exit.code
+		}
+	}
+
+	sh.history = loadHistory(sh.historyPath(), sh.historySize())
+
+	readLine := lineReader(os.Stdin)
+	if sh.interactive {
+		editor := newLineEditor(os.Stdin, os.Stdout, sh.ttyFd, sh.history, sh.complete)
+		readLine = editor.readLine
+	}
+
+	return exitCode(sh.runExitTrap(sh.runInput(readLine, sh.interactive)))
+}
@@ -0,0 +1,24 @@
+package shell
+
+import (
+	"errors"
+)
+
+func (sh *shell) executeSubshell(s *subshell, std *stdio) error {
+	std, closeFiles, err := sh.applyRedirection(s.redirs, std)
+	if err != nil {
+		return err
+	}
+	defer closeFiles()
+
+	sub := sh.subshell()
+	sub.std = std
+	err = sub.runExitTrap(sub.executeList(s.body, std))
+	sh.status = sub.status
+
+	var exit *shellExit
+	if errors.As(err, &exit) {
+		return statusFromCode(exit.code)
+	}
+	return err
+}
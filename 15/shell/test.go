@@ -0,0 +1,282 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+func testExpression(dir string, args []string) error {
+	name := args[0]
+	args = args[1:]
+	if name == "[" {
+		if len(args) == 0 || args[len(args)-1] != "]" {
+			return &statusError{code: 2, message: "[: missing `]'"}
+		}
+		args = args[:len(args)-1]
+	}
+
+	ok, err := evaluateTest(dir, args)
+	if err != nil {
+		return &statusError{code: 2, message: name + ": " + err.Error()}
+	}
+	if !ok {
+		return &statusError{code: 1}
+	}
+	return nil
+}
+
+func evaluateTest(dir string, args []string) (bool, error) {
+	switch len(args) {
+	case 0:
+		return false, nil
+	case 1:
+		return args[0] != "", nil
+	case 2:
+		if args[0] == "!" {
+			return args[1] == "", nil
+		}
+		if isUnaryTest(args[0]) {
+			return unaryTest(dir, args[0], args[1])
+		}
+		return false, fmt.Errorf("%s: unary operator expected", args[0])
+	case 3:
+		if isBinaryTest(args[1]) {
+			return binaryTest(dir, args[0], args[1], args[2])
+		}
+		if args[0] == "!" {
+			ok, err := evaluateTest(dir, args[1:])
+			return !ok, err
+		}
+		if args[0] == "(" && args[2] == ")" {
+			return args[1] != "", nil
+		}
+	case 4:
+		if args[0] == "!" {
+			ok, err := evaluateTest(dir, args[1:])
+			return !ok, err
+		}
+		if args[0] == "(" && args[3] == ")" {
+			return evaluateTest(dir, args[1:3])
+		}
+	}
+
+	p := &testParser{args: args, dir: dir}
+	ok, err := p.parseOr()
+	if err == nil && p.pos < len(p.args) {
+		err = errors.New("too many arguments")
+	}
+	return ok, err
+}
+
+type testParser struct {
+	args []string
+	pos  int
+	dir  string
+}
+
+func (p *testParser) peek() string {
+	if p.pos >= len(p.args) {
+		return ""
+	}
+	return p.args[p.pos]
+}
+
+func (p *testParser) next() (string, error) {
+	if p.pos >= len(p.args) {
+		return "", errors.New("argument expected")
+	}
+	p.pos++
+	return p.args[p.pos-1], nil
+}
+
+func (p *testParser) parseOr() (bool, error) {
+	res, err := p.parseAnd()
+	for err == nil && p.peek() == "-o" {
+		p.pos++
+		var ok bool
+		ok, err = p.parseAnd()
+		res = res || ok
+	}
+	return res, err
+}
+
+func (p *testParser) parseAnd() (bool, error) {
+	res, err := p.parseNot()
+	for err == nil && p.peek() == "-a" {
+		p.pos++
+		var ok bool
+		ok, err = p.parseNot()
+		res = res && ok
+	}
+	return res, err
+}
+
+func (p *testParser) parseNot() (bool, error) {
+	if p.peek() == "!" {
+		p.pos++
+		ok, err := p.parseNot()
+		return !ok, err
+	}
+	return p.parsePrimary()
+}
+
+func (p *testParser) parsePrimary() (bool, error) {
+	arg, err := p.next()
+	if err != nil {
+		return false, err
+	}
+
+	if arg == "(" {
+		ok, err := p.parseOr()
+		if err != nil {
+			return false, err
+		}
+		if p.peek() != ")" {
+			return false, errors.New("`)' expected")
+		}
+		p.pos++
+		return ok, nil
+	}
+
+	if isUnaryTest(arg) && p.pos < len(p.args) {
+		operand, _ := p.next()
+		return unaryTest(p.dir, arg, operand)
+	}
+
+	if isBinaryTest(p.peek()) {
+		op, _ := p.next()
+		right, err := p.next()
+		if err != nil {
+			return false, err
+		}
+		return binaryTest(p.dir, arg, op, right)
+	}
+
+	return arg != "", nil
+}
+
+func isUnaryTest(op string) bool {
+	switch op {
+	case "-e", "-f", "-d", "-r", "-w", "-x", "-s", "-L", "-h", "-p", "-S", "-b", "-c", "-z", "-n", "-t":
+		return true
+	}
+	return false
+}
+
+func isBinaryTest(op string) bool {
+	switch op {
+	case "=", "==", "!=", "<", ">", "-eq", "-ne", "-lt", "-le", "-gt", "-ge", "-nt", "-ot", "-ef":
+		return true
+	}
+	return false
+}
+
+func unaryTest(dir, op, arg string) (bool, error) {
+	switch op {
+	case "-z":
+		return arg == "", nil
+	case "-n":
+		return arg != "", nil
+	case "-t":
+		fd, err := strconv.Atoi(arg)
+		if err != nil {
+			return false, fmt.Errorf("%s: integer expression expected", arg)
+		}
+		return term.IsTerminal(fd), nil
+	}
+
+	path := resolvePath(dir, arg)
+	switch op {
+	case "-r":
+		return unix.Access(path, unix.R_OK) == nil, nil
+	case "-w":
+		return unix.Access(path, unix.W_OK) == nil, nil
+	case "-x":
+		return unix.Access(path, unix.X_OK) == nil, nil
+	case "-L", "-h":
+		info, err := os.Lstat(path)
+		return err == nil && info.Mode()&os.ModeSymlink != 0, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, nil
+	}
+
+	mode := info.Mode()
+	switch op {
+	case "-f":
+		return mode.IsRegular(), nil
+	case "-d":
+		return mode.IsDir(), nil
+	case "-s":
+		return info.Size() > 0, nil
+	case "-p":
+		return mode&os.ModeNamedPipe != 0, nil
+	case "-S":
+		return mode&os.ModeSocket != 0, nil
+	case "-b":
+		return mode&os.ModeDevice != 0 && mode&os.ModeCharDevice == 0, nil
+	case "-c":
+		return mode&os.ModeCharDevice != 0, nil
+	}
+	return true, nil
+}
+
+func binaryTest(dir, left, op, right string) (bool, error) {
+	switch op {
+	case "=", "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	case "<":
+		return left < right, nil
+	case ">":
+		return left > right, nil
+	case "-nt", "-ot", "-ef":
+		return compareFiles(resolvePath(dir, left), op, resolvePath(dir, right)), nil
+	}
+
+	a, err := strconv.ParseInt(left, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("%s: integer expression expected", left)
+	}
+	b, err := strconv.ParseInt(right, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("%s: integer expression expected", right)
+	}
+
+	switch op {
+	case "-eq":
+		return a == b, nil
+	case "-ne":
+		return a != b, nil
+	case "-lt":
+		return a < b, nil
+	case "-le":
+		return a <= b, nil
+	case "-gt":
+		return a > b, nil
+	default:
+		return a >= b, nil
+	}
+}
+
+func compareFiles(left, op, right string) bool {
+	a, errA := os.Stat(left)
+	b, errB := os.Stat(right)
+
+	switch op {
+	case "-nt":
+		return errA == nil && (errB != nil || a.ModTime().After(b.ModTime()))
+	case "-ot":
+		return errB == nil && (errA != nil || a.ModTime().Before(b.ModTime()))
+	default:
+		return errA == nil && errB == nil && os.SameFile(a, b)
+	}
+}
@@ -0,0 +1,69 @@
+#!/usr/bin/env l2sh
+# Тестовый скрипт для нашего shell: go build -o l2sh .. && ./l2sh test_script.sh
+# Каждая проверка при ошибке добавляет своё имя в $failed.
+
+failed=
+workdir=$(mktemp -d)
+cd "$workdir" || exit 1
+
+echo "=== Test 1: Builtin commands ==="
+test "$(pwd)" = "$workdir" || failed="$failed pwd"
+test "$(echo Hello   world)" = "Hello world" || failed="$failed echo"
+cd /tmp && test "$(pwd)" = /tmp || failed="$failed cd"
+cd ~ && test "$(pwd)" = "$(cd "$HOME" && pwd)" || failed="$failed cd-home"
+cd - > /dev/null && test "$(pwd)" = /tmp || failed="$failed cd-dash"
+cd "$workdir"
+
+echo "=== Test 2: External commands ==="
+test "$(echo test | cat -n | tr -d ' \t')" = 1test || failed="$failed cat"
+test "$(printf 'a\nb\n' | wc -l | tr -d ' ')" = 2 || failed="$failed wc"
+
+echo "=== Test 3: Pipelines ==="
+test "$(printf 'line1\nline2\nline3\n' | grep line | tail -1)" = line3 || failed="$failed pipeline"
+test "$(echo one two three | tr ' ' '\n' | sort | head -1)" = one || failed="$failed sort"
+
+echo "=== Test 4: Redirections ==="
+echo "content" > test_file.txt
+test "$(cat test_file.txt)" = content || failed="$failed redirect-out"
+echo "more content" >> test_file.txt
+test "$(wc -l < test_file.txt | tr -d ' ')" = 2 || failed="$failed redirect-append"
+test "$({ echo err >&2; } 2>&1 >/dev/null)" = err || failed="$failed redirect-dup"
+test "$(cat <<EOF
+heredoc $HOME
+EOF
+)" = "heredoc $HOME" || failed="$failed heredoc"
+
+echo "=== Test 5: Environment variables ==="
+NAME=value
+test "$NAME" = value || failed="$failed variable"
+test "$(NAME=temporary sh -c 'echo $NAME')" = temporary || failed="$failed env-prefix"
+test "$NAME" = value || failed="$failed env-restore"
+export NAME
+test "$(sh -c 'echo $NAME')" = value || failed="$failed export"
+test "${MISSING:-default} ${NAME#val}" = "default ue" || failed="$failed parameter"
+
+echo "=== Test 6: Conditional execution ==="
+result=
+true && result="${result}a"
+false || result="${result}b"
+false && result="${result}c"
+true || result="${result}d"
+test "$result" = ab || failed="$failed conditional"
+
+echo "=== Test 7: Error handling ==="
+cd nonexistent_directory 2> /dev/null || result=cd-failed
+test "$result" = cd-failed || failed="$failed cd-error"
+unknown_command 2> /dev/null
+test $? = 127 || failed="$failed not-found"
+
+echo "=== Test 8: Expansion ==="
+touch a.go b.go c.txt
+test "$(echo *.go)" = "a.go b.go" || failed="$failed glob"
+test "$(echo {1..3} x{a,b})" = "1 2 3 xa xb" || failed="$failed brace"
+
+cd /
+rm -rf "$workdir"
+
+test -z "$failed" || echo "FAILED:$failed"
+test -z "$failed" || exit 1
+echo "=== Tests completed ==="
@@ -0,0 +1,54 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestBuiltin(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("data"), 0644))
+	empty := filepath.Join(dir, "empty")
+	assert.NoError(t, os.WriteFile(empty, nil, 0644))
+
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"test"}, 1},
+		{[]string{"test", "abc"}, 0},
+		{[]string{"test", ""}, 1},
+		{[]string{"test", "-n"}, 0},
+		{[]string{"test", "-z", ""}, 0},
+		{[]string{"test", "-n", ""}, 1},
+		{[]string{"test", "!", ""}, 0},
+		{[]string{"test", "a", "=", "a"}, 0},
+		{[]string{"test", "a", "!=", "a"}, 1},
+		{[]string{"test", "10", "-gt", "9"}, 0},
+		{[]string{"test", "10", "-lt", "9"}, 1},
+		{[]string{"test", "x", "-eq", "1"}, 2},
+		{[]string{"test", "-f", file}, 0},
+		{[]string{"test", "-d", file}, 1},
+		{[]string{"test", "-d", dir}, 0},
+		{[]string{"test", "-e", filepath.Join(dir, "missing")}, 1},
+		{[]string{"test", "-s", file}, 0},
+		{[]string{"test", "-s", empty}, 1},
+		{[]string{"test", "!", "-f", dir}, 0},
+		{[]string{"test", "-f", file, "-a", "-d", dir}, 0},
+		{[]string{"test", "-f", dir, "-o", "-d", dir}, 0},
+		{[]string{"test", "(", "a", "=", "b", ")", "-o", "1", "-le", "1"}, 0},
+		{[]string{"test", "!", "(", "a", "=", "a", ")"}, 1},
+		{[]string{"[", "a", "]"}, 0},
+		{[]string{"[", "a", "=", "b", "]"}, 1},
+		{[]string{"[", "a"}, 2},
+		{[]string{"test", "-q", "a"}, 2},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.code, exitCode(testExpression("", test.args)), test.args)
+	}
+}
@@ -0,0 +1,24 @@
+echo hello   world
+mkdir sub && cd sub && pwd
+cd .. && pwd
+cd sub; cd -; pwd
+
+export GREETING=hi
+env | grep GREETING
+unset GREETING
+echo "[${GREETING}]"
+
+set -- a b c
+echo $# $*
+shift 2
+echo $1
+
+f() { local x=inner; echo $x; }
+x=outer; f; echo $x
+
+alias say='echo said'
+say it
+type cd say f if
+help cd
+true; echo $?
+false; echo $?
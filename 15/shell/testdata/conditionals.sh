@@ -0,0 +1,37 @@
+for n in 1 2 3 4; do
+    if [ $n -eq 1 ]; then
+        echo one
+    elif test $n = 2; then
+        echo two
+    else
+        echo many $n
+    fi
+done
+
+set -- 1 2 3 4 5
+while [ $# -gt 0 ]; do
+    i=$1
+    shift
+    [ $i -eq 2 ] && continue
+    [ $i -eq 4 ] && break
+    echo while $i
+done
+
+until [ -z "$i" ]; do
+    echo until $i
+    i=
+done
+
+for word in apple Banana cherry; do
+    case $word in
+    a*) echo "$word starts with a" ;;
+    [A-Z]*) echo "$word is capitalized" ;;
+    *) echo "$word is something else" ;;
+    esac
+done
+
+is_small() { [ $1 -lt 10 ] && return 0; return 1; }
+is_small 4 && echo 4 is small
+is_small 30 || echo 30 is not small
+true && false || echo fallback
+[ -d . ] && [ ! -f . ] && echo directory
@@ -0,0 +1,11 @@
+nosuchcommand
+echo $?
+cd nosuchdir
+echo $?
+shift 5
+[ 1 -eq x ]
+echo $?
+unalias nope
+help nope
+exit foo
+echo unreachable
@@ -0,0 +1,10 @@
+echo one two three | tr ' ' '\n' | sort -r
+echo hi | builtin grep -c hi
+true | false | true; echo ${PIPESTATUS[@]} $?
+set -o pipefail
+true | false | true; echo $?
+set +o pipefail
+! echo negated; echo $?
+! false | true; echo $?
+echo piped | { echo group; cat; }
+x=1; echo | x=2; echo $x
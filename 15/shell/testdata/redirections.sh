@@ -0,0 +1,25 @@
+echo first > out.txt
+echo second >> out.txt
+cat out.txt
+wc -l < out.txt
+
+{ echo out; echo err >&2; } 2>&1 > /dev/null | cat
+{ echo out; echo err >&2; } > both.txt 2>&1
+cat both.txt
+echo all &> all.txt; cat all.txt
+
+set -C
+echo clobber > out.txt
+echo forced >| out.txt
+set +C
+cat out.txt
+
+cat <<END
+heredoc in $HOME
+END
+cat <<-END
+	tabs stripped
+	END
+cat <<< "here string"
+echo bad >&5
+cat < missing.txt
@@ -0,0 +1,240 @@
+package shell
+
+import (
+	"L2/10/sorter"
+	"L2/12/searcher"
+	"L2/13/cutter"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// The sort, grep and cut builtins run the tools of 10/, 12/ and 13/ in
+// process. They are fallbacks: an executable of the same name in PATH takes
+// precedence, and `builtin sort` selects them explicitly.
+func init() {
+	registerFallbackBuiltin(sortBuiltin{})
+	registerFallbackBuiltin(grepBuiltin{})
+	registerFallbackBuiltin(cutBuiltin{})
+}
+
+func newToolFlags(inv *Invocation, synopsis string) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(inv.Args[0], pflag.ContinueOnError)
+	fs.SetOutput(inv.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(inv.Stderr, "usage: %s\n", synopsis)
+	}
+	return fs
+}
+
+func parseToolFlags(fs *pflag.FlagSet, inv *Invocation) error {
+	if err := fs.Parse(inv.Args[1:]); err != nil {
+		if !errors.Is(err, pflag.ErrHelp) {
+			fmt.Fprintf(inv.Stderr, "%s: %v\n", inv.Args[0], err)
+		}
+		fs.Usage()
+		return &statusError{code: 2}
+	}
+	return nil
+}
+
+// readInputLines reads the lines of the named files, or of stdin when no
+// files are given or a name is "-".
+func readInputLines(inv *Invocation, files []string) ([]string, error) {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	var lines []string
+	for _, name := range files {
+		reader := inv.Stdin
+		if name != "-" {
+			file, err := inv.Open(name)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", inv.Args[0], err)
+			}
+			defer file.Close()
+			reader = file
+		}
+
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %w", inv.Args[0], err)
+		}
+	}
+	return lines, nil
+}
+
+func writeLines(w io.Writer, lines []string) error {
+	out := bufio.NewWriter(w)
+	for _, line := range lines {
+		fmt.Fprintln(out, line)
+	}
+	return out.Flush()
+}
+
+type sortBuiltin struct{}
+
+const sortSynopsis = "sort [-bchMnru] [-k column] [file ...]"
+
+func (sortBuiltin) Name() string { return "sort" }
+
+func (sortBuiltin) Help() string {
+	return sortSynopsis + "\n\nSort lines of text. -k sorts by a space-separated column, -n numerically,\n" +
+		"-M by month name and -h by human-readable size; -r reverses, -u drops\n" +
+		"duplicate lines, -b ignores trailing blanks and -c only checks the order."
+}
+
+func (sortBuiltin) Run(inv *Invocation) error {
+	var opts sorter.Options
+	fs := newToolFlags(inv, sortSynopsis)
+	fs.IntVarP(&opts.Column, "key", "k", 0, "sort by column")
+	fs.BoolVarP(&opts.Numeric, "numeric-sort", "n", false, "compare numerically")
+	fs.BoolVarP(&opts.Reverse, "reverse", "r", false, "reverse the result")
+	fs.BoolVarP(&opts.Unique, "unique", "u", false, "output unique lines")
+	fs.BoolVarP(&opts.Month, "month-sort", "M", false, "compare month names")
+	fs.BoolVarP(&opts.IgnoreTrailing, "ignore-trailing-blanks", "b", false, "ignore trailing blanks")
+	fs.BoolVarP(&opts.HumanReadable, "human-numeric-sort", "h", false, "compare human-readable sizes")
+	check := fs.BoolP("check", "c", false, "check whether input is sorted")
+	if err := parseToolFlags(fs, inv); err != nil {
+		return err
+	}
+
+	lines, err := readInputLines(inv, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	if *check {
+		if !sorter.IsSorted(lines, opts) {
+			return &statusError{code: 1, message: "sort: input is not sorted"}
+		}
+		return nil
+	}
+
+	if opts.Unique {
+		lines = sorter.RemoveDuplicates(lines)
+	}
+	sorter.Sort(lines, opts)
+	return writeLines(inv.Stdout, lines)
+}
+
+type grepBuiltin struct{}
+
+const grepSynopsis = "grep [-cFinv] [-A n] [-B n] [-C n] pattern [file ...]"
+
+func (grepBuiltin) Name() string { return "grep" }
+
+func (grepBuiltin) Help() string {
+	return grepSynopsis + "\n\nPrint lines containing pattern. -F matches whole lines only, -i ignores\n" +
+		"case, -v selects non-matching lines, -c prints the count and -n line\n" +
+		"numbers; -A, -B and -C add lines of context. Exits with 1 if nothing\n" +
+		"was selected."
+}
+
+func (grepBuiltin) Run(inv *Invocation) error {
+	var opts searcher.Options
+	fs := newToolFlags(inv, grepSynopsis)
+	fs.IntVarP(&opts.After, "after-context", "A", 0, "print lines after each match")
+	fs.IntVarP(&opts.Before, "before-context", "B", 0, "print lines before each match")
+	fs.IntVarP(&opts.Around, "context", "C", 0, "print lines around each match")
+	count := fs.BoolP("count", "c", false, "print the number of matching lines")
+	fs.BoolVarP(&opts.Ignore, "ignore-case", "i", false, "ignore case")
+	fs.BoolVarP(&opts.Inverse, "invert-match", "v", false, "select non-matching lines")
+	fs.BoolVarP(&opts.Fix, "line-regexp", "F", false, "match whole lines")
+	number := fs.BoolP("line-number", "n", false, "print line numbers")
+	if err := parseToolFlags(fs, inv); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return &statusError{code: 2}
+	}
+
+	lines, err := readInputLines(inv, fs.Args()[1:])
+	if err != nil {
+		return &statusError{code: 2, message: err.Error()}
+	}
+
+	pattern := fs.Arg(0)
+	selected := searcher.Count(lines, pattern, opts)
+	if *count {
+		_, err = fmt.Fprintln(inv.Stdout, selected)
+	} else {
+		var res []string
+		for _, i := range searcher.Search(lines, pattern, opts) {
+			line := lines[i]
+			if *number {
+				separator := "-"
+				if searcher.Match(line, pattern, opts) {
+					separator = ":"
+				}
+				line = strconv.Itoa(i+1) + separator + line
+			}
+			res = append(res, line)
+		}
+		err = writeLines(inv.Stdout, res)
+	}
+	if err != nil {
+		return err
+	}
+
+	if selected == 0 {
+		return &statusError{code: 1}
+	}
+	return nil
+}
+
+type cutBuiltin struct{}
+
+const cutSynopsis = "cut -f list [-d delim] [-s] [file ...]"
+
+func (cutBuiltin) Name() string { return "cut" }
+
+func (cutBuiltin) Help() string {
+	return cutSynopsis + "\n\nPrint the selected fields of each line. The list is comma-separated\n" +
+		"field numbers and ranges such as 1,3-5 or 2-; -d sets the delimiter, a\n" +
+		"tab by default, and -s skips lines without one."
+}
+
+func (cutBuiltin) Run(inv *Invocation) error {
+	fs := newToolFlags(inv, cutSynopsis)
+	list := fs.StringP("fields", "f", "", "select these fields")
+	delimiter := fs.StringP("delimiter", "d", "\t", "field delimiter")
+	separated := fs.BoolP("only-delimited", "s", false, "skip lines without delimiters")
+	if err := parseToolFlags(fs, inv); err != nil {
+		return err
+	}
+	if *delimiter == "" {
+		return errors.New("cut: the delimiter must not be empty")
+	}
+
+	fields, err := cutter.ParseFields(*list)
+	if err != nil {
+		return fmt.Errorf("cut: %v", err)
+	}
+
+	lines, err := readInputLines(inv, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	var res []string
+	for _, line := range lines {
+		indexes := fields.Indexes(strings.Count(line, *delimiter) + 1)
+		if parts, ok := cutter.Cut(line, *delimiter, indexes); ok {
+			res = append(res, strings.Join(parts, *delimiter))
+		} else if !*separated {
+			res = append(res, line)
+		}
+	}
+	return writeLines(inv.Stdout, res)
+}
@@ -0,0 +1,103 @@
+package shell
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTools(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"data":   "b 2\na 10\nc 1\n",
+		"dups":   "x\ny\nx\n\nz\n",
+		"months": "Mar\nJan\nFeb\n",
+		"sizes":  "2K\n1M\n512\n",
+		"tabs":   "a\tb\nnone\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	tests := []struct {
+		script string
+		out    string
+		errOut string
+		code   int
+	}{
+		{"builtin sort data", "a 10\nb 2\nc 1\n", "", 0},
+		{"builtin sort -n -k 2 data", "c 1\nb 2\na 10\n", "", 0},
+		{"builtin sort -r < data", "c 1\nb 2\na 10\n", "", 0},
+		{"builtin sort -u dups", "\nx\ny\nz\n", "", 0},
+		{"builtin sort -M months", "Jan\nFeb\nMar\n", "", 0},
+		{"builtin sort -h sizes", "512\n2K\n1M\n", "", 0},
+		{"builtin sort -c months | builtin sort -c", "", "sort: input is not sorted\n", 0},
+		{"builtin sort -c data", "", "sort: input is not sorted\n", 1},
+		{"builtin sort -z", "", "sort: unknown shorthand flag: 'z' in -z\nusage: sort [-bchMnru] [-k column] [file ...]\n", 2},
+		{"builtin sort nope", "", "sort: open nope: no such file or directory\n", 1},
+		{"builtin grep a data", "a 10\n", "", 0},
+		{"builtin grep -v a data", "b 2\nc 1\n", "", 0},
+		{"builtin grep -i A data", "a 10\n", "", 0},
+		{"builtin grep -c 1 data", "2\n", "", 0},
+		{"builtin grep -F 'c 1' data", "c 1\n", "", 0},
+		{"builtin grep -F c data || echo $?", "1\n", "", 0},
+		{"builtin grep -n -A 1 b data", "1:b 2\n2-a 10\n", "", 0},
+		{"builtin grep -n -C 1 a data", "1-b 2\n2:a 10\n3-c 1\n", "", 0},
+		{"echo x y | builtin grep y | builtin grep x", "x y\n", "", 0},
+		{"builtin grep", "", "usage: grep [-cFinv] [-A n] [-B n] [-C n] pattern [file ...]\n", 2},
+		{"builtin cut -d ' ' -f 2 data", "2\n10\n1\n", "", 0},
+		{"echo a:b:c:d | builtin cut -d : -f 1,3-", "a:c:d\n", "", 0},
+		{"echo a:b:c:d | builtin cut -d : -f -2", "a:b\n", "", 0},
+		{"builtin cut -f 2 tabs", "b\nnone\n", "", 0},
+		{"builtin cut -s -f 2 tabs", "b\n", "", 0},
+		{"echo a | builtin cut -f 0", "", "cut: invalid field list: 0\n", 1},
+		{"echo a | builtin cut", "", "cut: you must specify a list of fields\n", 1},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		sh := newTestShell(&out, &errOut)
+
+		script := "cd " + dir + "\n" + test.script
+		err := sh.runInput(lineReader(strings.NewReader(script)), false)
+		assert.Equal(t, test.code, exitCode(err), test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Equal(t, test.errOut, errOut.String(), test.script)
+	}
+}
+
+func TestToolsPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "bin")
+	assert.NoError(t, os.MkdirAll(bin, 0755))
+	for _, name := range []string{"sort", "grep", "cut"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\necho external "+name+"\n"), 0755))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "data"), []byte("b\na\n"), 0644))
+
+	tests := []struct {
+		script string
+		out    string
+	}{
+		{"PATH=" + bin + "; echo b a | sort", "external sort\n"},
+		{"PATH=" + bin + "; grep -q x; cut -c1", "external grep\nexternal cut\n"},
+		{"PATH=" + bin + "; builtin sort data", "a\nb\n"},
+		{"PATH=" + bin + "; type sort", "sort is " + bin + "/sort\n"},
+		{"PATH=/nonexistent; type sort; sort data", "sort is a shell builtin\na\nb\n"},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		sh := newTestShell(&out, &errOut)
+
+		script := "cd " + dir + "\n" + test.script
+		err := sh.runInput(lineReader(strings.NewReader(script)), false)
+		assert.Equal(t, 0, exitCode(err), test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Empty(t, errOut.String(), test.script)
+	}
+}
@@ -0,0 +1,218 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// exitTrap is the key of the EXIT trap in shell.traps.
+const exitTrap = syscall.Signal(0)
+
+func (sh *shell) trap(args []string, std *stdio) error {
+	if len(args) > 0 && args[0] == "-l" {
+		return listSignals(nil, std)
+	}
+	if len(args) == 0 || args[0] == "-p" {
+		return sh.printTraps(args, std)
+	}
+	if args[0] == "--" {
+		args = args[1:]
+	}
+
+	action := "-"
+	if len(args) > 1 {
+		action, args = args[0], args[1:]
+	} else if _, err := parseTrapSignal(args[0]); err != nil {
+		return errors.New("trap: usage: trap [-lp] [[action] signal ...]")
+	}
+
+	var err error
+	for _, spec := range args {
+		sig, parseErr := parseTrapSignal(spec)
+		if parseErr != nil {
+			fmt.Fprintln(std.err(), parseErr)
+			err = &statusError{code: 1}
+			continue
+		}
+		sh.setTrap(sig, action)
+	}
+	return err
+}
+
+func (sh *shell) setTrap(sig syscall.Signal, action string) {
+	if action == "-" {
+		delete(sh.traps, sig)
+	} else {
+		if sh.traps == nil {
+			sh.traps = make(map[syscall.Signal]string)
+		}
+		sh.traps[sig] = action
+	}
+
+	if sig != exitTrap {
+		sh.notifyTraps()
+	}
+}
+
+// notifyTraps subscribes sh.signals to the trapped signals and ignores
+// those with an empty action, so that commands started by the shell inherit
+// the ignored disposition. Signals that are no longer ignored get their
+// default action back, and the SIGINT handler of catchInterrupts is kept.
+func (sh *shell) notifyTraps() {
+	if sh.signals == nil {
+		sh.signals = make(chan os.Signal, 16)
+	}
+	if len(sh.ignored) > 0 {
+		signal.Notify(sh.signals, sh.ignored...)
+	}
+	signal.Stop(sh.signals)
+
+	var sigs []os.Signal
+	sh.ignored = nil
+	for sig, action := range sh.traps {
+		switch {
+		case sig == exitTrap:
+		case action == "":
+			sh.ignored = append(sh.ignored, sig)
+		default:
+			sigs = append(sigs, sig)
+		}
+	}
+	if len(sh.ignored) > 0 {
+		signal.Ignore(sh.ignored...)
+	}
+	if len(sigs) > 0 {
+		signal.Notify(sh.signals, sigs...)
+	}
+	if action, ok := sh.traps[syscall.SIGINT]; sh.interrupts != nil && (!ok || action != "") {
+		signal.Notify(sh.interrupts, os.Interrupt)
+	}
+}
+
+// catchInterrupts keeps SIGINT from killing the shell. A non-interactive
+// shell then stops before its next command unless SIGINT is trapped.
+func (sh *shell) catchInterrupts() {
+	sh.interrupts = make(chan os.Signal, 1)
+	signal.Notify(sh.interrupts, os.Interrupt)
+	if sh.interactive {
+		return
+	}
+
+	interrupts, interrupted := sh.interrupts, new(atomic.Bool)
+	sh.interrupted = interrupted
+	go func() {
+		for range interrupts {
+			interrupted.Store(true)
+		}
+	}()
+}
+
+func (sh *shell) printTraps(args []string, std *stdio) error {
+	var sigs []syscall.Signal
+	if len(args) > 1 {
+		for _, spec := range args[1:] {
+			sig, err := parseTrapSignal(spec)
+			if err != nil {
+				return &statusError{code: 1, message: err.Error()}
+			}
+			sigs = append(sigs, sig)
+		}
+	} else {
+		for sig := range sh.traps {
+			sigs = append(sigs, sig)
+		}
+		sort.Slice(sigs, func(i, j int) bool { return sigs[i] < sigs[j] })
+	}
+
+	for _, sig := range sigs {
+		action, ok := sh.traps[sig]
+		if !ok {
+			continue
+		}
+		name := "EXIT"
+		if sig != exitTrap {
+			name = unix.SignalName(sig)
+		}
+		fmt.Fprintf(std.out(), "trap -- %s %s\n", shellQuote(action), name)
+	}
+	return nil
+}
+
+func parseTrapSignal(spec string) (syscall.Signal, error) {
+	if spec == "0" || strings.EqualFold(spec, "EXIT") {
+		return exitTrap, nil
+	}
+	sig, err := parseSignal(spec)
+	if err != nil || sig == 0 {
+		return 0, fmt.Errorf("trap: %s: invalid signal specification", spec)
+	}
+	return sig, nil
+}
+
+// runTraps runs the actions of the trapped signals received since the last
+// call. It is called between commands, so a handler never interrupts one.
+func (sh *shell) runTraps() error {
+	if sh.interrupted != nil && sh.interrupted.Load() {
+		if _, ok := sh.traps[syscall.SIGINT]; !ok {
+			return &shellExit{code: 130}
+		}
+		sh.interrupted.Store(false)
+	}
+
+	for {
+		select {
+		case sig := <-sh.signals:
+			action := sh.traps[sig.(syscall.Signal)]
+			if action == "" {
+				continue
+			}
+			if err := sh.runTrap(action); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// runExitTrap runs the EXIT trap when the shell finishes with err. The exit
+// status is kept unless the trap calls exit.
+func (sh *shell) runExitTrap(err error) error {
+	action := sh.traps[exitTrap]
+	if action == "" {
+		return err
+	}
+	delete(sh.traps, exitTrap)
+
+	sh.status = exitCode(err)
+	if trapErr := sh.runTrap(action); trapErr != nil {
+		return trapErr
+	}
+	return err
+}
+
+func (sh *shell) runTrap(action string) error {
+	list, err := parseWithAliases(action, sh.aliases)
+	if err != nil {
+		fmt.Fprintf(sh.std.err(), "%s: %s\n", sh.name, err)
+		return nil
+	}
+
+	status := sh.status
+	err = sh.executeList(list, sh.std)
+
+	var exit *shellExit
+	if errors.As(err, &exit) {
+		return err
+	}
+	sh.status = status
+	return nil
+}
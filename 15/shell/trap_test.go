@@ -0,0 +1,110 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrap(t *testing.T) {
+	tests := []struct {
+		script string
+		out    string
+		errOut string
+		code   int
+	}{
+		{"trap 'echo bye' EXIT; echo hi", "hi\nbye\n", "", 0},
+		{"trap 'echo bye $?' 0; false", "bye 1\n", "", 1},
+		{"trap 'echo bye; exit 4' EXIT; exit 2", "bye\n", "", 4},
+		{"trap 'echo no' EXIT; trap - EXIT; echo hi", "hi\n", "", 0},
+		{"(trap 'echo inner' EXIT; echo sub); echo outer", "sub\ninner\nouter\n", "", 0},
+		{"echo $(trap 'echo trapped' EXIT; echo subst)", "subst trapped\n", "", 0},
+		{"trap 'echo usr1' USR1; trap '' SIGUSR2; trap 'echo x' EXIT; trap; trap -p USR2", "trap -- 'echo x' EXIT\ntrap -- 'echo usr1' SIGUSR1\ntrap -- '' SIGUSR2\ntrap -- '' SIGUSR2\nx\n", "", 0},
+		{"trap 'echo caught; false' USR1; kill -USR1 $$; sleep 0.2; echo after $?", "caught\nafter 0\n", "", 0},
+		{"trap 'echo caught; exit 5' USR1; kill -USR1 $$; sleep 0.2; echo unreachable", "caught\n", "", 5},
+		{"trap '' USR2; kill -USR2 $$; sleep 0.2; echo survived", "survived\n", "", 0},
+		{"trap '' USR2; sh -c 'kill -USR2 $$; echo child survived'; trap - USR2", "child survived\n", "", 0},
+		{"sh -c 'kill -INT $$'; echo unreachable", "", "", 130},
+		{"trap 'echo trapped' INT; sh -c 'kill -INT $$'; echo $?", "130\n", "", 0},
+		{"trap 'echo x' NOPE", "", "trap: NOPE: invalid signal specification\n", 1},
+		{"trap 'echo x'", "", "trap: usage: trap [-lp] [[action] signal ...]\n", 1},
+		{"trap -l | head -2", " 1) SIGHUP\n 2) SIGINT\n", "", 0},
+	}
+
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		in, err := NewInterpreter(&Config{
+			Stdout: &out,
+			Stderr: &errOut,
+			Env:    []string{"PATH=" + os.Getenv("PATH")},
+		})
+		assert.NoError(t, err)
+
+		code, err := in.Run(context.Background(), test.script)
+		assert.NoError(t, err, test.script)
+		assert.Equal(t, test.code, code, test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+		assert.Equal(t, test.errOut, errOut.String(), test.script)
+	}
+}
+
+func TestTrapKeepsOtherHandlers(t *testing.T) {
+	received := make(chan os.Signal, 4)
+	signal.Notify(received, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(received)
+
+	tests := []string{
+		"trap 'echo x' USR1; trap - USR1",
+		"trap 'echo x' USR1 USR2; trap - USR2",
+	}
+
+	for _, script := range tests {
+		in, err := NewInterpreter(&Config{Stdout: io.Discard, Stderr: io.Discard})
+		assert.NoError(t, err)
+		_, err = in.Run(context.Background(), script)
+		assert.NoError(t, err, script)
+
+		for _, sig := range []syscall.Signal{syscall.SIGUSR1, syscall.SIGUSR2} {
+			assert.NoError(t, syscall.Kill(os.Getpid(), sig), script)
+			select {
+			case got := <-received:
+				assert.Equal(t, sig, got, script)
+			case <-time.After(time.Second):
+				t.Errorf("%s: %v was not delivered to the other handler", script, sig)
+			}
+		}
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	tests := []struct {
+		script string
+		out    string
+		code   int
+	}{
+		{"kill -INT $$; sleep 0.2; echo unreachable", "", 130},
+		{"trap 'echo bye' EXIT; kill -INT $$; sleep 0.2; echo unreachable", "bye\n", 130},
+		{"trap 'echo trapped' INT; kill -INT $$; sleep 0.2; echo after", "trapped\nafter\n", 0},
+		{"trap '' INT; kill -INT $$; sleep 0.2; trap - INT; echo after", "after\n", 0},
+		{"kill -INT $$ & sleep 0.5; echo unreachable", "", 130},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		sh := newTestShell(&out, &bytes.Buffer{})
+		sh.catchInterrupts()
+
+		err := sh.runExitTrap(sh.runInput(lineReader(strings.NewReader(test.script)), false))
+		signal.Stop(sh.interrupts)
+		assert.Equal(t, test.code, exitCode(err), test.script)
+		assert.Equal(t, test.out, out.String(), test.script)
+	}
+}
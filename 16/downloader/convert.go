@@ -0,0 +1,115 @@
+package downloader
+
+import (
+	"L2/16/parser"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type savedFile struct {
+	url         string
+	localPath   string
+	contentType string
+}
+
+func (d *Downloader) markSaved(urlStr, localPath, contentType string) {
+	d.savedMutex.Lock()
+	defer d.savedMutex.Unlock()
+
+	d.saved[d.visited.NormalizeURL(urlStr)] = savedFile{
+		url:         urlStr,
+		localPath:   localPath,
+		contentType: contentType,
+	}
+}
+
+func (d *Downloader) savedPath(urlStr string) (string, bool) {
+	d.savedMutex.Lock()
+	defer d.savedMutex.Unlock()
+
+	file, ok := d.saved[d.visited.NormalizeURL(urlStr)]
+	return file.localPath, ok
+}
+
+func (d *Downloader) relocateSaved(oldPath, newPath string) {
+	d.savedMutex.Lock()
+	defer d.savedMutex.Unlock()
+
+	for key, file := range d.saved {
+		if file.localPath == oldPath {
+			file.localPath = newPath
+			d.saved[key] = file
+		}
+	}
+}
+
+// convertLinks rewrites the links in the saved pages and stylesheets to the
+// relative paths of the local copies, like wget -k. Links to files that were
+// not downloaded are made absolute.
+func (d *Downloader) convertLinks() {
+	d.savedMutex.Lock()
+	files := make([]savedFile, 0, len(d.saved))
+	for _, file := range d.saved {
+		files = append(files, file)
+	}
+	d.savedMutex.Unlock()
+
+	for _, file := range files {
+		var rewrite func([]byte, *url.URL, func(string) string) []byte
+		switch {
+		case strings.Contains(file.contentType, "text/html"):
+			rewrite = parser.RewriteHTML
+		case strings.Contains(file.contentType, "text/css"):
+			rewrite = parser.RewriteCSS
+		default:
+			continue
+		}
+
+		if err := d.convertFile(file, rewrite); err != nil {
+			log.Printf("Failed to convert links in %s: %v", file.localPath, err)
+		}
+	}
+}
+
+func (d *Downloader) convertFile(file savedFile, rewrite func([]byte, *url.URL, func(string) string) []byte) error {
+	base, err := url.Parse(file.url)
+	if err != nil {
+		return err
+	}
+
+	fullPath := filepath.Join(d.config.OutputDir, file.localPath)
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return err
+	}
+
+	converted := rewrite(content, base, func(link string) string {
+		return d.localLink(file.localPath, link)
+	})
+	d.state.markConverted(d.visited.NormalizeURL(file.url))
+	return os.WriteFile(fullPath, converted, 0644)
+}
+
+// localLink returns the path of the local copy of link relative to the file
+// at fromPath, or link itself if it was not downloaded.
+func (d *Downloader) localLink(fromPath, link string) string {
+	target, ok := d.savedPath(link)
+	if !ok {
+		return link
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(fromPath), target)
+	if err != nil {
+		return link
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	ref := &url.URL{Path: filepath.ToSlash(rel), Fragment: u.Fragment}
+	return ref.String()
+}
@@ -3,6 +3,7 @@ package downloader
 import (
 	"L2/16/parser"
 	"L2/16/storage"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -23,15 +24,66 @@ type Config struct {
 	Timeout       time.Duration
 	UserAgent     string
 	RespectRobots bool
+	Order         Order
+	ConvertLinks  bool
+
+	// AdjustExtension adds .html or .css to the names of pages and
+	// stylesheets saved without it.
+	AdjustExtension bool
+
+	// RateLimit is the number of requests per second and BandwidthLimit
+	// the number of bytes per second allowed to each host, or 0 for no
+	// limit. Failed requests are retried up to Retries times, backing off
+	// from RetryDelay.
+	RateLimit      float64
+	BandwidthLimit int64
+	Retries        int
+	RetryDelay     time.Duration
+
+	// Links are followed on the host of BaseURL and on Domains and their
+	// subdomains, but never on ExcludeDomains. SpanHosts fetches the page
+	// requisites from any other host too.
+	Domains        []string
+	ExcludeDomains []string
+	SpanHosts      bool
+
+	// IncludeDirs and ExcludeDirs are path prefixes of the URLs to follow
+	// or skip, and NoParent keeps the crawl below the directory of BaseURL.
+	IncludeDirs []string
+	ExcludeDirs []string
+	NoParent    bool
+
+	// Accept and Reject are suffixes or glob patterns of the file names to
+	// keep. URLs must match AcceptRegex and not RejectRegex to be fetched.
+	Accept      []string
+	Reject      []string
+	AcceptRegex string
+	RejectRegex string
 }
 
 type Downloader struct {
-	config    *Config
-	client    *http.Client
-	queue     chan *downloadTask
-	visited   *storage.URLStorage
-	wg        sync.WaitGroup
-	robotsTxt *RobotsTxt
+	config   *Config
+	client   *http.Client
+	frontier *frontier
+	visited  *storage.URLStorage
+	wg       sync.WaitGroup
+	filter   *urlFilter
+	state    *crawlState
+
+	robots      map[string]*hostRobots
+	robotsMutex sync.Mutex
+
+	saved      map[string]savedFile
+	savedMutex sync.Mutex
+
+	limiters      map[string]*hostLimiter
+	limitersMutex sync.Mutex
+
+	paths      map[string]string
+	pathsMutex sync.Mutex
+
+	links      map[string][]parser.Link
+	linksMutex sync.Mutex
 }
 
 type downloadTask struct {
@@ -49,11 +101,14 @@ func NewDownloader(config *Config) *Downloader {
 	}
 
 	return &Downloader{
-		config:    config,
-		client:    client,
-		queue:     make(chan *downloadTask, 1000),
-		visited:   storage.NewURLStorage(),
-		robotsTxt: NewRobotsTxt(),
+		config:   config,
+		client:   client,
+		frontier: newFrontier(config.Order),
+		visited:  storage.NewURLStorage(),
+		robots:   make(map[string]*hostRobots),
+		saved:    make(map[string]savedFile),
+		limiters: make(map[string]*hostLimiter),
+		links:    make(map[string][]parser.Link),
 	}
 }
 
@@ -63,80 +118,194 @@ func (d *Downloader) Start() error {
 		return err
 	}
 
+	d.filter, err = newURLFilter(d.config)
+	if err != nil {
+		return err
+	}
+
+	d.state, err = loadCrawlState(d.config.OutputDir)
+	if err != nil {
+		return err
+	}
+	d.paths = d.state.paths()
+
 	if d.config.RespectRobots {
-		err = d.robotsTxt.Load(d.client, baseURL)
+		robotsTxt := NewRobotsTxt(d.config.UserAgent)
+		err = robotsTxt.Load(d.client, baseURL)
 		if err != nil {
 			return err
 		}
+		for _, sitemap := range robotsTxt.Sitemaps() {
+			log.Printf("Found sitemap %s", sitemap)
+		}
+		entry := &hostRobots{}
+		entry.once.Do(func() { entry.robotsTxt = robotsTxt })
+		d.robots[baseURL.Host] = entry
 	}
 
-	for i := 0; i < d.config.Concurrency; i++ {
-		d.wg.Add(1)
-		go d.worker()
-	}
-
-	d.queue <- &downloadTask{
+	d.frontier.push(&downloadTask{
 		URL:    d.config.BaseURL,
 		Depth:  0,
 		IsPage: true,
-	}
+	})
 
-	close(d.queue)
+	for i := 0; i < max(d.config.Concurrency, 1); i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
 	d.wg.Wait()
 
-	return nil
+	if d.config.ConvertLinks {
+		d.convertLinks()
+	}
+
+	return d.state.finish()
 }
 
 func (d *Downloader) worker() {
 	defer d.wg.Done()
 
-	for task := range d.queue {
+	for {
+		task, ok := d.frontier.pop()
+		if !ok {
+			return
+		}
 		d.processTask(task)
+		d.frontier.done()
 	}
 }
 
 func (d *Downloader) processTask(task *downloadTask) {
-	if d.visited.Has(task.URL) {
+	if task.Depth > d.config.MaxDepth {
 		return
 	}
-	d.visited.Add(task.URL)
 
-	if d.config.RespectRobots && !d.robotsTxt.Allowed(task.URL) {
-		log.Printf("Skipping %s (disallowed by robots.txt)", task.URL)
+	first, shallower := d.visited.Visit(task.URL, task.Depth)
+	if shallower {
+		d.revisit(task)
+	}
+	if !first {
 		return
 	}
 
-	if task.Depth > d.config.MaxDepth {
+	base, err := url.Parse(task.URL)
+	if err != nil {
+		log.Printf("Failed to parse base URL: %v", err)
+		return
+	}
+
+	if d.config.RespectRobots && !d.robotsTxt(base).Allowed(task.URL) {
+		log.Printf("Skipping %s (disallowed by robots.txt)", task.URL)
 		return
 	}
 
-	content, contentType, err := d.downloadContent(task.URL)
+	localPath, contentType, err := d.downloadWithRetry(task.URL)
 	if err != nil {
 		log.Printf("Failed to download %s: %v", task.URL, err)
 		return
 	}
+	if d.filter.keeps(base) {
+		d.markSaved(task.URL, localPath, contentType)
+	} else {
+		defer d.discard(task.URL)
+	}
 
-	err = d.saveContent(task.URL, content)
+	var extract func([]byte, *url.URL) []parser.Link
+	switch {
+	case task.IsPage && strings.Contains(contentType, "text/html"):
+		extract = parser.ExtractLinks
+	case strings.Contains(contentType, "text/css"):
+		extract = parser.ExtractCSSLinks
+	default:
+		return
+	}
+
+	content, err := os.ReadFile(filepath.Join(d.config.OutputDir, localPath))
 	if err != nil {
-		log.Printf("Failed to save %s: %v", task.URL, err)
+		log.Printf("Failed to read %s: %v", localPath, err)
 		return
 	}
+	d.followLinks(task.URL, extract(content, base))
+}
 
-	if task.IsPage && strings.Contains(contentType, "text/html") {
-		d.extractLinks(task.URL, content, task.Depth)
+// followLinks records the links of a downloaded file and queues them from
+// the shallowest depth the file was reached at so far.
+func (d *Downloader) followLinks(urlStr string, links []parser.Link) {
+	d.linksMutex.Lock()
+	defer d.linksMutex.Unlock()
+
+	d.links[d.visited.NormalizeURL(urlStr)] = links
+	depth, _ := d.visited.Depth(urlStr)
+	d.extractLinks(links, depth)
+}
+
+// revisit follows the links of a file again when it is reached at a
+// shallower depth than before, so that a crawl in depth-first order does
+// not stop short below a URL it first found deep down. If the file is
+// still being processed, followLinks picks up the new depth instead.
+func (d *Downloader) revisit(task *downloadTask) {
+	d.linksMutex.Lock()
+	defer d.linksMutex.Unlock()
+
+	if links, ok := d.links[d.visited.NormalizeURL(task.URL)]; ok {
+		d.extractLinks(links, task.Depth)
 	}
 }
 
-func (d *Downloader) downloadContent(urlStr string) ([]byte, string, error) {
+// download saves urlStr in the output directory and returns its local path
+// and content type. Files fetched before the current run was interrupted are
+// kept, older ones are revalidated with a conditional request, and a partial
+// file is resumed with a range request.
+func (d *Downloader) download(urlStr string) (string, string, error) {
+	parseURL, err := url.Parse(urlStr)
+	if err != nil {
+		return "", "", err
+	}
+
+	key := d.visited.NormalizeURL(urlStr)
+	entry, known := d.state.get(key)
+	partPath := d.fullPath(entry.Path) + ".part"
+	if _, err := os.Stat(d.fullPath(entry.Path)); known && !entry.Partial && err != nil {
+		known = false
+	}
+	if known && d.state.fresh(entry) {
+		return entry.Path, entry.ContentType, nil
+	}
+
 	req, err := http.NewRequest("GET", urlStr, nil)
 	if err != nil {
-		return nil, "", err
+		return "", "", err
 	}
 	req.Header.Set("User-Agent", d.config.UserAgent)
 
+	var offset int64
+	switch {
+	case known && entry.Partial:
+		info, err := os.Stat(partPath)
+		validator := entry.ETag
+		if validator == "" || strings.HasPrefix(validator, "W/") {
+			validator = entry.LastModified
+		}
+		if err == nil && info.Size() > 0 && validator != "" {
+			offset = info.Size()
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			req.Header.Set("If-Range", validator)
+		}
+	case known && !entry.Converted:
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	limiter := d.limiter(parseURL)
+	limiter.wait()
+
 	resp, err := d.client.Do(req)
 	if err != nil {
-		return nil, "", err
+		return "", "", err
 	}
 
 	defer func() {
@@ -146,89 +315,170 @@ func (d *Downloader) downloadContent(urlStr string) ([]byte, string, error) {
 		}
 	}()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if retryAfter > 0 && retryAfter <= maxRetryAfter {
+		limiter.pause(retryAfter)
 	}
 
-	content, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, "", err
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if !known || entry.Partial {
+			return "", "", &httpError{status: resp.StatusCode}
+		}
+		d.state.put(key, entry)
+		return entry.Path, entry.ContentType, nil
+	case http.StatusPartialContent:
+		if offset == 0 || !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			if err = os.Remove(partPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return "", "", err
+			}
+			d.state.remove(key)
+			return "", "", fmt.Errorf("unexpected range %q", resp.Header.Get("Content-Range"))
+		}
+		flag = os.O_WRONLY | os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset == 0 {
+			return "", "", &httpError{status: resp.StatusCode}
+		}
+		if err = os.Remove(partPath); err != nil {
+			return "", "", err
+		}
+		d.state.remove(key)
+		return d.download(urlStr)
+	default:
+		return "", "", &httpError{status: resp.StatusCode, retryAfter: retryAfter}
 	}
 
-	return content, resp.Header.Get("Content-Type"), nil
-}
-
-func (d *Downloader) saveContent(urlStr string, content []byte) error {
-	parseURL, err := url.Parse(urlStr)
+	contentType := resp.Header.Get("Content-Type")
+	localPath := entry.Path
+	if !known {
+		localPath = d.urlToLocalPath(parseURL)
+		if d.config.AdjustExtension {
+			localPath = withExtension(localPath, contentType)
+		}
+	}
+	localPath, err = d.claimPath(key, localPath)
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
-	localPath := d.urlToLocalPath(parseURL)
-	fullPath := filepath.Join(d.config.OutputDir, localPath)
-
-	dir := filepath.Dir(fullPath)
-	if err = os.MkdirAll(dir, 0755); err != nil {
-		return err
+	file, err := os.OpenFile(d.fullPath(localPath)+".part", flag, 0644)
+	if err != nil {
+		return "", "", err
 	}
 
-	if err = os.WriteFile(fullPath, content, 0644); err != nil {
-		return err
+	entry = stateEntry{
+		URL:          urlStr,
+		Path:         localPath,
+		ContentType:  contentType,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Partial:      true,
 	}
+	d.state.put(key, entry)
 
-	return nil
-}
-
-func (d *Downloader) urlToLocalPath(u *url.URL) string {
-	path := u.Path
-	if path == "" || strings.HasSuffix(path, "/") {
-		path += "index.html"
+	_, err = io.Copy(file, limiter.reader(resp.Body))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
 	}
-
-	path = strings.TrimPrefix(path, "/")
-
-	if path == "" {
-		path = "index.html"
+	if err != nil {
+		return "", "", err
 	}
 
-	if u.Host != "" {
-		path = filepath.Join(u.Host, path)
+	entry.Path, err = d.placeFile(key, localPath)
+	if err != nil {
+		return "", "", err
 	}
+	entry.Partial = false
+	d.state.put(key, entry)
 
-	return filepath.Clean(path)
+	return entry.Path, entry.ContentType, nil
 }
 
-func (d *Downloader) extractLinks(baseURL string, content []byte, currentDepth int) {
-	base, err := url.Parse(baseURL)
-	if err != nil {
-		log.Printf("Failed to parse base URL: %v", err)
-		return
-	}
-
-	links := parser.ExtractLinks(content, base)
+// extractLinks queues the links found in a file. Requisites keep the depth
+// of the file so pages at the maximum depth still get their images, styles
+// and fonts.
+func (d *Downloader) extractLinks(links []parser.Link, currentDepth int) {
+	var tasks []*downloadTask
 	for _, link := range links {
-		if !d.isSameDomain(link) {
+		linkURL, err := url.Parse(link.URL)
+		if err != nil || !d.filter.follows(linkURL, link.Requisite) {
+			continue
+		}
+		isPage := parser.IsPageURL(link.URL)
+		if !isPage && !d.filter.keeps(linkURL) {
 			continue
 		}
 
-		d.queue <- &downloadTask{
-			URL:    link,
-			Depth:  currentDepth + 1,
-			IsPage: parser.IsPageURL(link),
+		depth := currentDepth + 1
+		if link.Requisite {
+			depth = currentDepth
 		}
+		if depth > d.config.MaxDepth {
+			continue
+		}
+		if visited, ok := d.visited.Depth(link.URL); ok && visited <= depth {
+			continue
+		}
+
+		tasks = append(tasks, &downloadTask{
+			URL:    link.URL,
+			Depth:  depth,
+			IsPage: isPage,
+		})
 	}
+	d.frontier.push(tasks...)
 }
 
-func (d *Downloader) isSameDomain(link string) bool {
-	linkURL, err := url.Parse(link)
-	if err != nil {
-		return false
-	}
+// hostRobots is the robots.txt of one host, loaded once.
+type hostRobots struct {
+	once      sync.Once
+	robotsTxt *RobotsTxt
+}
 
-	baseURL, err := url.Parse(d.config.BaseURL)
-	if err != nil {
-		return false
+// robotsTxt returns the robots.txt of the host of u, loading it on first
+// use. A host whose robots.txt cannot be fetched is not restricted. The
+// fetch happens outside robotsMutex, so only the workers waiting for the
+// same host are held up by a slow server.
+func (d *Downloader) robotsTxt(u *url.URL) *RobotsTxt {
+	d.robotsMutex.Lock()
+	entry, ok := d.robots[u.Host]
+	if !ok {
+		entry = &hostRobots{}
+		d.robots[u.Host] = entry
 	}
+	d.robotsMutex.Unlock()
+
+	entry.once.Do(func() {
+		r := NewRobotsTxt(d.config.UserAgent)
+		if err := r.Load(d.client, u); err != nil {
+			log.Printf("Failed to load robots.txt of %s: %v", u.Host, err)
+			r = NewRobotsTxt(d.config.UserAgent)
+		}
+		entry.robotsTxt = r
+	})
+	return entry.robotsTxt
+}
 
-	return linkURL.Host == baseURL.Host || linkURL.Host == ""
+// discard removes the file of a page that was only downloaded to follow
+// its links.
+func (d *Downloader) discard(urlStr string) {
+	key := d.visited.NormalizeURL(urlStr)
+
+	d.pathsMutex.Lock()
+	defer d.pathsMutex.Unlock()
+
+	entry, ok := d.state.get(key)
+	if !ok {
+		return
+	}
+	if err := os.Remove(d.fullPath(entry.Path)); err != nil {
+		log.Printf("Failed to remove %s: %v", entry.Path, err)
+		return
+	}
+	delete(d.paths, entry.Path)
+	d.state.remove(key)
+	log.Printf("Removed %s (rejected)", entry.Path)
 }
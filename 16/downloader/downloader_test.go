@@ -0,0 +1,491 @@
+package downloader
+
+import (
+	"L2/16/storage"
+	"crypto/sha1"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSite = map[string]string{
+	"/robots.txt":     "User-agent: *\nDisallow: /private/\n",
+	"/":               `<a href="a/">a</a> <a href="b.html">b</a> <link rel="stylesheet" href="style.css"> <img src="img.png"> <a href="http://external.example/x">x</a> <a href="#top">top</a>`,
+	"/a/":             `<a href="deep.html">deep</a> <a href="../">up</a> <img src="/img.png">`,
+	"/a/deep.html":    `<a href="deeper.html">deeper</a>`,
+	"/a/deeper.html":  `<a href="/">home</a>`,
+	"/b.html":         `<a href="/private/p.html">private</a> <a href="/a/">a</a>`,
+	"/private/p.html": `secret`,
+	"/style.css":      `body { color: red; }`,
+	"/img.png":        "\x89PNG",
+}
+
+type testServer struct {
+	*httptest.Server
+	site      map[string]string
+	modTimes  map[string]time.Time
+	requests  []string
+	responses []string
+	headers   map[string]http.Header
+	mutex     sync.Mutex
+}
+
+// newTestServer serves site with an ETag and a Last-Modified time for every
+// path, and records the requests and response statuses.
+func newTestServer(t *testing.T, site map[string]string) *testServer {
+	server := &testServer{
+		site:     maps.Clone(site),
+		modTimes: make(map[string]time.Time),
+		headers:  make(map[string]http.Header),
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		body, ok := server.site[r.URL.Path]
+		modTime := server.modTimes[r.URL.Path]
+		if r.URL.Path != "/robots.txt" {
+			server.requests = append(server.requests, r.URL.Path)
+			server.headers[r.URL.Path] = r.Header.Clone()
+		}
+		server.mutex.Unlock()
+
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		switch filepath.Ext(r.URL.Path) {
+		case ".css":
+			w.Header().Set("Content-Type", "text/css")
+		case ".png":
+			w.Header().Set("Content-Type", "image/png")
+		case ".txt":
+			w.Header().Set("Content-Type", "text/plain")
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		if modTime.IsZero() {
+			modTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		}
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sha1.Sum([]byte(body))))
+
+		// The status is recorded before the client can see the response.
+		recorder := &statusRecorder{ResponseWriter: w, record: func(status int) {
+			if r.URL.Path != "/robots.txt" {
+				server.mutex.Lock()
+				server.responses = append(server.responses, fmt.Sprintf("%s %d", r.URL.Path, status))
+				server.mutex.Unlock()
+			}
+		}}
+		http.ServeContent(recorder, r, r.URL.Path, modTime, strings.NewReader(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// set changes the content of a path and moves its modification time on.
+func (s *testServer) set(path, body string, modTime time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.site[path] = body
+	s.modTimes[path] = modTime
+}
+
+func (s *testServer) reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests = nil
+	s.responses = nil
+	s.headers = make(map[string]http.Header)
+}
+
+// hostDir returns the directory of the files of a server in the output.
+func hostDir(serverURL string) string {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return ""
+	}
+	return safeName(u.Host)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	record func(int)
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.record(status)
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func TestDownloaderMirror(t *testing.T) {
+	all := []string{"/", "/a/", "/a/deep.html", "/a/deeper.html", "/b.html", "/style.css", "/img.png"}
+
+	tests := []struct {
+		order       Order
+		concurrency int
+		maxDepth    int
+		requests    []string
+		ordered     bool
+	}{
+		{BreadthFirst, 1, 5, []string{"/", "/a/", "/b.html", "/style.css", "/img.png", "/a/deep.html", "/a/deeper.html"}, true},
+		{DepthFirst, 1, 5, []string{"/", "/a/", "/a/deep.html", "/a/deeper.html", "/img.png", "/b.html", "/style.css"}, true},
+		{BreadthFirst, 4, 5, all, false},
+		{DepthFirst, 4, 5, all, false},
+		{BreadthFirst, 4, 1, []string{"/", "/a/", "/b.html", "/style.css", "/img.png"}, false},
+		{BreadthFirst, 0, 0, []string{"/", "/style.css", "/img.png"}, false},
+	}
+
+	for _, test := range tests {
+		server := newTestServer(t, testSite)
+		output := t.TempDir()
+
+		d := NewDownloader(&Config{
+			BaseURL:       server.URL + "/",
+			OutputDir:     output,
+			MaxDepth:      test.maxDepth,
+			Concurrency:   test.concurrency,
+			Timeout:       5 * time.Second,
+			UserAgent:     "WebMirror/1.0",
+			RespectRobots: true,
+			Order:         test.order,
+		})
+
+		done := make(chan error, 1)
+		go func() {
+			done <- d.Start()
+		}()
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("order %d, concurrency %d: crawl did not finish", test.order, test.concurrency)
+		}
+
+		if test.ordered {
+			assert.Equal(t, test.requests, server.requests, "order %d request order", test.order)
+		} else {
+			assert.ElementsMatch(t, test.requests, server.requests, "order %d, depth %d requests", test.order, test.maxDepth)
+		}
+
+		host, err := url.Parse(server.URL)
+		require.NoError(t, err)
+		for _, path := range all {
+			local := d.urlToLocalPath(&url.URL{Host: host.Host, Path: path})
+			content, err := os.ReadFile(filepath.Join(output, local))
+			if assert.Equal(t, slices.Contains(test.requests, path), err == nil, "%s saved", path) && err == nil {
+				assert.Equal(t, testSite[path], string(content), "%s content", path)
+			}
+		}
+		assert.NoFileExists(t, filepath.Join(output, hostDir(server.URL), "private", "p.html"), "disallowed by robots.txt")
+	}
+}
+
+func TestFrontier(t *testing.T) {
+	tests := []struct {
+		order Order
+		want  []string
+	}{
+		{BreadthFirst, []string{"root", "a", "b", "a1", "a2", "b1"}},
+		{DepthFirst, []string{"root", "a", "a1", "a2", "b", "b1"}},
+	}
+
+	children := map[string][]string{
+		"root": {"a", "b"},
+		"a":    {"a1", "a2"},
+		"b":    {"b1"},
+	}
+
+	for _, test := range tests {
+		f := newFrontier(test.order)
+		f.push(&downloadTask{URL: "root"})
+
+		var got []string
+		for {
+			task, ok := f.pop()
+			if !ok {
+				break
+			}
+			got = append(got, task.URL)
+
+			var tasks []*downloadTask
+			for _, child := range children[task.URL] {
+				tasks = append(tasks, &downloadTask{URL: child})
+			}
+			f.push(tasks...)
+			f.done()
+		}
+
+		assert.Equal(t, test.want, got, "order %d", test.order)
+	}
+}
+
+func TestDownloaderShallowerPath(t *testing.T) {
+	// Depth-first, c.html is first reached through a.html at the maximum
+	// depth, and then again directly from the start page.
+	site := map[string]string{
+		"/":       `<a href="a.html">a</a> <a href="c.html">c</a>`,
+		"/a.html": `<a href="c.html">c</a>`,
+		"/c.html": `<a href="e.html">e</a>`,
+		"/e.html": `e`,
+	}
+
+	for _, order := range []Order{BreadthFirst, DepthFirst} {
+		server := newTestServer(t, site)
+
+		d := NewDownloader(&Config{
+			BaseURL:     server.URL + "/",
+			OutputDir:   t.TempDir(),
+			MaxDepth:    2,
+			Concurrency: 1,
+			Timeout:     5 * time.Second,
+			UserAgent:   "WebMirror/1.0",
+			Order:       order,
+		})
+		require.NoError(t, d.Start())
+
+		assert.ElementsMatch(t, []string{"/", "/a.html", "/c.html", "/e.html"}, server.requests, "order %d", order)
+	}
+}
+
+func TestDownloaderSlowRobots(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			<-release
+		}
+		_, _ = w.Write([]byte("png"))
+	}))
+	t.Cleanup(slow.Close)
+	var once sync.Once
+	t.Cleanup(func() { once.Do(func() { close(release) }) })
+
+	server := newTestServer(t, map[string]string{
+		"/":       `<img src="` + slow.URL + `/logo.png"> <a href="1.html">1</a> <a href="2.html">2</a> <a href="3.html">3</a>`,
+		"/1.html": "1",
+		"/2.html": "2",
+		"/3.html": "3",
+	})
+
+	d := NewDownloader(&Config{
+		BaseURL:       server.URL + "/",
+		OutputDir:     t.TempDir(),
+		MaxDepth:      1,
+		Concurrency:   2,
+		Timeout:       5 * time.Second,
+		UserAgent:     "WebMirror/1.0",
+		RespectRobots: true,
+		SpanHosts:     true,
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- d.Start()
+	}()
+
+	assert.Eventually(t, func() bool {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		return len(server.requests) == 4
+	}, 2*time.Second, 10*time.Millisecond, "other hosts are crawled while a robots.txt loads")
+	once.Do(func() { close(release) })
+	require.NoError(t, <-done)
+}
+
+func TestDownloaderConvertLinks(t *testing.T) {
+	server := newTestServer(t, testSite)
+	output := t.TempDir()
+
+	d := NewDownloader(&Config{
+		BaseURL:       server.URL + "/",
+		OutputDir:     output,
+		MaxDepth:      5,
+		Concurrency:   4,
+		Timeout:       5 * time.Second,
+		UserAgent:     "WebMirror/1.0",
+		RespectRobots: true,
+		ConvertLinks:  true,
+	})
+	require.NoError(t, d.Start())
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"index.html", `<a href="a/index.html">a</a> <a href="b.html">b</a> <link rel="stylesheet" href="style.css"> <img src="img.png"> <a href="http://external.example/x">x</a> <a href="#top">top</a>`},
+		{"a/index.html", `<a href="deep.html">deep</a> <a href="../index.html">up</a> <img src="../img.png">`},
+		{"a/deeper.html", `<a href="../index.html">home</a>`},
+		{"b.html", `<a href="` + server.URL + `/private/p.html">private</a> <a href="a/index.html">a</a>`},
+		{"style.css", testSite["/style.css"]},
+	}
+
+	for _, test := range tests {
+		content, err := os.ReadFile(filepath.Join(output, hostDir(server.URL), test.path))
+		require.NoError(t, err, test.path)
+		assert.Equal(t, test.want, string(content), test.path)
+	}
+}
+
+func TestDownloaderRequisites(t *testing.T) {
+	site := map[string]string{
+		"/":                  `<link rel="stylesheet" href="css/style.css"><div style="background: url(hero.jpg)"></div><img srcset="s.png 1x, l.png 2x"><video poster="poster.jpg"></video><meta http-equiv="refresh" content="30; url=next.html">`,
+		"/css/style.css":     `@import "fonts.css"; body { background: url(../bg.png) }`,
+		"/css/fonts.css":     `@font-face { src: url(fonts/a.woff2) }`,
+		"/css/fonts/a.woff2": "wOF2",
+		"/hero.jpg":          "jpg",
+		"/s.png":             "png",
+		"/l.png":             "png",
+		"/poster.jpg":        "jpg",
+		"/bg.png":            "png",
+		"/next.html":         `next`,
+	}
+
+	tests := []struct {
+		maxDepth int
+		requests []string
+	}{
+		{0, []string{"/", "/css/style.css", "/css/fonts.css", "/css/fonts/a.woff2", "/bg.png", "/hero.jpg", "/s.png", "/l.png", "/poster.jpg"}},
+		{1, []string{"/", "/css/style.css", "/css/fonts.css", "/css/fonts/a.woff2", "/bg.png", "/hero.jpg", "/s.png", "/l.png", "/poster.jpg", "/next.html"}},
+	}
+
+	for _, test := range tests {
+		server := newTestServer(t, site)
+
+		d := NewDownloader(&Config{
+			BaseURL:     server.URL + "/",
+			OutputDir:   t.TempDir(),
+			MaxDepth:    test.maxDepth,
+			Concurrency: 4,
+			Timeout:     5 * time.Second,
+			UserAgent:   "WebMirror/1.0",
+		})
+		require.NoError(t, d.Start())
+
+		assert.ElementsMatch(t, test.requests, server.requests, "depth %d", test.maxDepth)
+	}
+}
+
+func newTestDownloader(server *testServer, output string) *Downloader {
+	return NewDownloader(&Config{
+		BaseURL:       server.URL + "/",
+		OutputDir:     output,
+		MaxDepth:      5,
+		Concurrency:   2,
+		Timeout:       5 * time.Second,
+		UserAgent:     "WebMirror/1.0",
+		RespectRobots: true,
+	})
+}
+
+func TestDownloaderIncremental(t *testing.T) {
+	server := newTestServer(t, testSite)
+	output := t.TempDir()
+	require.NoError(t, newTestDownloader(server, output).Start())
+
+	server.reset()
+	server.set("/b.html", `<a href="/a/">changed</a>`, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, newTestDownloader(server, output).Start())
+
+	assert.ElementsMatch(t, []string{
+		"/ 304", "/a/ 304", "/a/deep.html 304", "/a/deeper.html 304", "/b.html 200", "/style.css 304", "/img.png 304",
+	}, server.responses, "second run")
+	assert.Equal(t, fmt.Sprintf(`"%x"`, sha1.Sum([]byte(testSite["/a/"]))), server.headers["/a/"].Get("If-None-Match"), "If-None-Match")
+	assert.Equal(t, "Mon, 01 Jan 2024 00:00:00 GMT", server.headers["/a/"].Get("If-Modified-Since"), "If-Modified-Since")
+
+	for path, want := range map[string]string{"index.html": testSite["/"], "b.html": `<a href="/a/">changed</a>`, "a/deeper.html": testSite["/a/deeper.html"]} {
+		content, err := os.ReadFile(filepath.Join(output, hostDir(server.URL), path))
+		require.NoError(t, err, path)
+		assert.Equal(t, want, string(content), path)
+	}
+}
+
+func TestDownloaderResume(t *testing.T) {
+	server := newTestServer(t, testSite)
+	output := t.TempDir()
+	require.NoError(t, newTestDownloader(server, output).Start())
+
+	// Make the run look interrupted before /a/deeper.html was saved.
+	state, err := loadCrawlState(output)
+	require.NoError(t, err)
+	state.Run--
+	key := storage.NewURLStorage().NormalizeURL(server.URL + "/a/deeper.html")
+	require.NoError(t, os.Remove(filepath.Join(output, state.Entries[key].Path)))
+	delete(state.Entries, key)
+	require.NoError(t, state.save())
+
+	server.reset()
+	require.NoError(t, newTestDownloader(server, output).Start())
+	assert.Equal(t, []string{"/a/deeper.html 200"}, server.responses, "resumed run")
+
+	server.reset()
+	require.NoError(t, newTestDownloader(server, output).Start())
+	assert.Len(t, server.responses, 7, "next run revalidates everything")
+	assert.NotContains(t, server.responses, "/a/deeper.html 200", "next run")
+}
+
+func TestDownloaderRangeResume(t *testing.T) {
+	big := strings.Repeat("0123456789abcdef", 4096)
+	site := map[string]string{
+		"/":        `<img src="big.png">`,
+		"/big.png": big,
+	}
+
+	tests := []struct {
+		etag   string
+		status string
+		ranged bool
+	}{
+		{fmt.Sprintf(`"%x"`, sha1.Sum([]byte(big))), "/big.png 206", true},
+		{`"stale"`, "/big.png 200", true},
+		{"", "/big.png 200", false},
+	}
+
+	for _, test := range tests {
+		server := newTestServer(t, site)
+		output := t.TempDir()
+
+		localPath := filepath.Join(hostDir(server.URL), "big.png")
+		require.NoError(t, os.MkdirAll(filepath.Join(output, hostDir(server.URL)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(output, localPath+".part"), []byte(big[:10000]), 0644))
+
+		state := &crawlState{
+			Run:      1,
+			Finished: false,
+			Entries: map[string]*stateEntry{
+				storage.NewURLStorage().NormalizeURL(server.URL + "/big.png"): {
+					URL:     server.URL + "/big.png",
+					Path:    localPath,
+					ETag:    test.etag,
+					Run:     1,
+					Partial: true,
+				},
+			},
+			path: filepath.Join(output, stateFile),
+		}
+		require.NoError(t, state.save())
+
+		require.NoError(t, newTestDownloader(server, output).Start())
+
+		assert.Contains(t, server.responses, test.status, test.etag)
+		if test.ranged {
+			assert.Equal(t, "bytes=10000-", server.headers["/big.png"].Get("Range"), test.etag)
+			assert.Equal(t, test.etag, server.headers["/big.png"].Get("If-Range"), test.etag)
+		} else {
+			assert.Empty(t, server.headers["/big.png"].Get("Range"), "no validator")
+		}
+
+		content, err := os.ReadFile(filepath.Join(output, localPath))
+		require.NoError(t, err)
+		assert.Equal(t, big, string(content), test.etag)
+		assert.NoFileExists(t, filepath.Join(output, localPath+".part"), test.etag)
+	}
+}
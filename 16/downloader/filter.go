@@ -0,0 +1,148 @@
+package downloader
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// urlFilter holds the rules that decide which links the mirror follows and
+// which of the downloaded files it keeps, like the options of wget.
+type urlFilter struct {
+	baseHost       string
+	parentDir      string
+	domains        []string
+	excludeDomains []string
+	spanHosts      bool
+	includeDirs    []string
+	excludeDirs    []string
+	noParent       bool
+	accept         []string
+	reject         []string
+	acceptRegex    *regexp.Regexp
+	rejectRegex    *regexp.Regexp
+}
+
+func newURLFilter(config *Config) (*urlFilter, error) {
+	baseURL, err := url.Parse(config.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &urlFilter{
+		baseHost:       strings.ToLower(baseURL.Host),
+		parentDir:      baseURL.Path[:strings.LastIndex(baseURL.Path, "/")+1],
+		domains:        config.Domains,
+		excludeDomains: config.ExcludeDomains,
+		spanHosts:      config.SpanHosts,
+		includeDirs:    config.IncludeDirs,
+		excludeDirs:    config.ExcludeDirs,
+		noParent:       config.NoParent,
+		accept:         config.Accept,
+		reject:         config.Reject,
+	}
+	if config.AcceptRegex != "" {
+		if f.acceptRegex, err = regexp.Compile(config.AcceptRegex); err != nil {
+			return nil, err
+		}
+	}
+	if config.RejectRegex != "" {
+		if f.rejectRegex, err = regexp.Compile(config.RejectRegex); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// follows reports whether a link is downloaded. Links are followed on the
+// mirrored host and the included domains, and with spanHosts the page
+// requisites are fetched from any host that is not excluded. Requisites
+// may also leave the start directory under noParent, as with wget -p.
+func (f *urlFilter) follows(u *url.URL, requisite bool) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	host := strings.ToLower(u.Host)
+	switch {
+	case matchDomain(f.excludeDomains, host):
+		return false
+	case host == f.baseHost, matchDomain(f.domains, host):
+	case !requisite || !f.spanHosts:
+		return false
+	}
+
+	if f.noParent && !requisite && host == f.baseHost && !strings.HasPrefix(u.Path, f.parentDir) {
+		return false
+	}
+	if len(f.includeDirs) > 0 && !matchDir(f.includeDirs, u.Path) {
+		return false
+	}
+	if matchDir(f.excludeDirs, u.Path) {
+		return false
+	}
+
+	if f.acceptRegex != nil && !f.acceptRegex.MatchString(u.String()) {
+		return false
+	}
+	return f.rejectRegex == nil || !f.rejectRegex.MatchString(u.String())
+}
+
+// keeps reports whether the file of a URL passes the accept and reject
+// lists. Pages that do not are still downloaded for their links.
+func (f *urlFilter) keeps(u *url.URL) bool {
+	name := u.Path[strings.LastIndex(u.Path, "/")+1:]
+	if len(f.accept) > 0 && !matchName(f.accept, name) {
+		return false
+	}
+	return !matchName(f.reject, name)
+}
+
+// matchDomain reports whether host is one of domains or a subdomain of one.
+// A domain without a port matches the host on any port.
+func matchDomain(domains []string, host string) bool {
+	hostname := host
+	if u, err := url.Parse("//" + host); err == nil {
+		hostname = u.Hostname()
+	}
+
+	for _, domain := range domains {
+		domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+		name := hostname
+		if strings.Contains(domain, ":") {
+			name = host
+		}
+		if domain != "" && (name == domain || strings.HasSuffix(name, "."+domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDir reports whether p is in one of the directories given as path
+// prefixes.
+func matchDir(dirs []string, p string) bool {
+	for _, dir := range dirs {
+		dir = "/" + strings.Trim(dir, "/")
+		if dir == "/" || p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchName reports whether a file name matches one of patterns, each a
+// suffix such as "jpg" or a glob such as "*.tar.*".
+func matchName(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if strings.ContainsAny(pattern, "*?[") {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		} else if pattern != "" && strings.HasSuffix(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package downloader
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLFilterFollows(t *testing.T) {
+	tests := []struct {
+		config    Config
+		url       string
+		requisite bool
+		want      bool
+	}{
+		{Config{}, "http://example.com/a", false, true},
+		{Config{}, "http://EXAMPLE.com/a", false, true},
+		{Config{}, "ftp://example.com/a", false, false},
+		{Config{}, "http://www.example.com/a", false, false},
+		{Config{}, "http://cdn.test/a.png", true, false},
+		{Config{SpanHosts: true}, "http://cdn.test/a.png", true, true},
+		{Config{SpanHosts: true}, "http://cdn.test/page", false, false},
+		{Config{SpanHosts: true, ExcludeDomains: []string{"cdn.test"}}, "http://cdn.test/a.png", true, false},
+		{Config{Domains: []string{"example.com"}}, "http://www.example.com/a", false, true},
+		{Config{Domains: []string{".example.com"}}, "http://static.www.example.com:8080/a", false, true},
+		{Config{Domains: []string{"example.com"}}, "http://badexample.com/a", false, false},
+		{Config{Domains: []string{"example.com:8080"}}, "http://www.example.com:8080/a", false, true},
+		{Config{Domains: []string{"example.com:8080"}}, "http://www.example.com/a", false, false},
+		{Config{Domains: []string{"example.com"}, ExcludeDomains: []string{"ads.example.com"}}, "http://x.ads.example.com/a", false, false},
+		{Config{ExcludeDomains: []string{"example.com"}}, "http://example.com/a", false, false},
+		{Config{IncludeDirs: []string{"/docs"}}, "http://example.com/docs", false, true},
+		{Config{IncludeDirs: []string{"/docs/"}}, "http://example.com/docs/a/b", false, true},
+		{Config{IncludeDirs: []string{"docs"}}, "http://example.com/docsx", false, false},
+		{Config{IncludeDirs: []string{"/docs", "/img"}}, "http://example.com/img/a.png", true, true},
+		{Config{ExcludeDirs: []string{"/private"}}, "http://example.com/private/a", false, false},
+		{Config{ExcludeDirs: []string{"/private"}}, "http://example.com/privateer", false, true},
+		{Config{NoParent: true}, "http://example.com/docs/v1/b", false, true},
+		{Config{NoParent: true}, "http://example.com/docs/v2/", false, false},
+		{Config{NoParent: true}, "http://example.com/static/style.css", true, true},
+		{Config{NoParent: true, Domains: []string{"example.org"}}, "http://example.org/a", false, true},
+		{Config{AcceptRegex: `/docs/`}, "http://example.com/docs/v1/a", false, true},
+		{Config{AcceptRegex: `/docs/`}, "http://example.com/blog/a", false, false},
+		{Config{RejectRegex: `[?&]sort=`}, "http://example.com/list?page=2&sort=asc", false, false},
+		{Config{RejectRegex: `[?&]sort=`}, "http://example.com/list?page=2", false, true},
+	}
+
+	for _, test := range tests {
+		test.config.BaseURL = "http://example.com/docs/v1/index.html"
+		f, err := newURLFilter(&test.config)
+		require.NoError(t, err)
+
+		u, err := url.Parse(test.url)
+		require.NoError(t, err, test.url)
+		assert.Equal(t, test.want, f.follows(u, test.requisite), "%+v: %s", test.config, test.url)
+	}
+
+	_, err := newURLFilter(&Config{BaseURL: "http://example.com/", AcceptRegex: "("})
+	assert.Error(t, err, "invalid regex")
+}
+
+func TestURLFilterKeeps(t *testing.T) {
+	tests := []struct {
+		accept []string
+		reject []string
+		url    string
+		want   bool
+	}{
+		{nil, nil, "http://example.com/", true},
+		{[]string{"jpg", "png"}, nil, "http://example.com/a.png", true},
+		{[]string{"jpg", "png"}, nil, "http://example.com/a.gif", false},
+		{[]string{"jpg"}, nil, "http://example.com/", false},
+		{[]string{"jpg"}, nil, "http://example.com/a.jpg?size=2", true},
+		{[]string{"*.tar.*"}, nil, "http://example.com/a.tar.gz", true},
+		{[]string{"*.tar.*"}, nil, "http://example.com/a.tgz", false},
+		{nil, []string{"gif"}, "http://example.com/a.gif", false},
+		{nil, []string{"thumb-*"}, "http://example.com/img/thumb-1.png", false},
+		{nil, []string{"thumb-*"}, "http://example.com/thumb-1/a.png", true},
+		{[]string{"png"}, []string{"thumb-*"}, "http://example.com/thumb-1.png", false},
+	}
+
+	for _, test := range tests {
+		f, err := newURLFilter(&Config{BaseURL: "http://example.com/", Accept: test.accept, Reject: test.reject})
+		require.NoError(t, err)
+
+		u, err := url.Parse(test.url)
+		require.NoError(t, err, test.url)
+		assert.Equal(t, test.want, f.keeps(u), "accept %v, reject %v: %s", test.accept, test.reject, test.url)
+	}
+}
+
+func TestDownloaderFilters(t *testing.T) {
+	cdn := newTestServer(t, map[string]string{
+		"/robots.txt":    "User-agent: *\nDisallow: /private/\n",
+		"/logo.png":      "png",
+		"/private/x.png": "png",
+		"/page.html":     "cdn page",
+	})
+	site := map[string]string{
+		"/docs/":           `<a href="guide.html">g</a> <a href="../blog/">blog</a> <a href="` + cdn.URL + `/page.html">cdn</a> <img src="` + cdn.URL + `/logo.png"> <img src="` + cdn.URL + `/private/x.png"> <img src="/static/a.gif"> <img src="/static/b.png">`,
+		"/docs/guide.html": `<a href="old/">old</a>`,
+		"/docs/old/":       `old`,
+		"/blog/":           `blog`,
+		"/static/a.gif":    "gif",
+		"/static/b.png":    "png",
+	}
+
+	tests := []struct {
+		config      Config
+		requests    []string
+		cdnRequests []string
+		files       []string
+	}{
+		{
+			Config{},
+			[]string{"/docs/", "/docs/guide.html", "/docs/old/", "/blog/", "/static/a.gif", "/static/b.png"},
+			nil,
+			[]string{"docs/index.html", "docs/guide.html", "docs/old/index.html", "blog/index.html", "static/a.gif", "static/b.png"},
+		},
+		{
+			Config{SpanHosts: true, NoParent: true, Reject: []string{"gif"}, ExcludeDirs: []string{"/docs/old"}},
+			[]string{"/docs/", "/docs/guide.html", "/static/b.png"},
+			[]string{"/logo.png"},
+			[]string{"docs/index.html", "docs/guide.html", "static/b.png"},
+		},
+		{
+			Config{Accept: []string{"png"}, Domains: []string{strings.TrimPrefix(cdn.URL, "http://")}, RejectRegex: `/old/`},
+			[]string{"/docs/", "/docs/guide.html", "/blog/", "/static/b.png"},
+			[]string{"/page.html", "/logo.png"},
+			[]string{"static/b.png"},
+		},
+	}
+
+	for _, test := range tests {
+		server := newTestServer(t, site)
+		cdn.reset()
+		output := t.TempDir()
+
+		config := test.config
+		config.BaseURL = server.URL + "/docs/"
+		config.OutputDir = output
+		config.MaxDepth = 2
+		config.Concurrency = 2
+		config.Timeout = 5 * time.Second
+		config.UserAgent = "WebMirror/1.0"
+		config.RespectRobots = true
+		require.NoError(t, NewDownloader(&config).Start())
+
+		assert.ElementsMatch(t, test.requests, server.requests, "%+v", test.config)
+		assert.ElementsMatch(t, test.cdnRequests, cdn.requests, "%+v: other host", test.config)
+
+		var files []string
+		root := filepath.Join(output, hostDir(server.URL))
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				rel, _ := filepath.Rel(root, path)
+				files = append(files, filepath.ToSlash(rel))
+			}
+			return err
+		})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, test.files, files, "%+v: saved files", test.config)
+	}
+}
@@ -0,0 +1,90 @@
+package downloader
+
+import (
+	"sync"
+)
+
+// Order selects the task a worker takes next from the frontier.
+type Order int
+
+const (
+	BreadthFirst Order = iota
+	DepthFirst
+)
+
+// frontier is an unbounded work queue that counts the tasks being processed.
+// It is exhausted once it is empty and no task in flight can add more.
+type frontier struct {
+	tasks    []*downloadTask
+	order    Order
+	inFlight int
+	mutex    sync.Mutex
+	cond     *sync.Cond
+}
+
+func newFrontier(order Order) *frontier {
+	f := &frontier{
+		order: order,
+	}
+	f.cond = sync.NewCond(&f.mutex)
+	return f
+}
+
+// push adds tasks found together. Depth-first order still visits them in
+// the order given.
+func (f *frontier) push(tasks ...*downloadTask) {
+	if len(tasks) == 0 {
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.order == DepthFirst {
+		for i := len(tasks) - 1; i >= 0; i-- {
+			f.tasks = append(f.tasks, tasks[i])
+		}
+	} else {
+		f.tasks = append(f.tasks, tasks...)
+	}
+	f.cond.Broadcast()
+}
+
+// pop waits for the next task and marks it in flight. It returns false when
+// the frontier is exhausted.
+func (f *frontier) pop() (*downloadTask, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for len(f.tasks) == 0 {
+		if f.inFlight == 0 {
+			return nil, false
+		}
+		f.cond.Wait()
+	}
+
+	var task *downloadTask
+	if f.order == DepthFirst {
+		last := len(f.tasks) - 1
+		task = f.tasks[last]
+		f.tasks[last] = nil
+		f.tasks = f.tasks[:last]
+	} else {
+		task = f.tasks[0]
+		f.tasks[0] = nil
+		f.tasks = f.tasks[1:]
+	}
+	f.inFlight++
+	return task, true
+}
+
+// done marks a task returned by pop as processed.
+func (f *frontier) done() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.inFlight--
+	if f.inFlight == 0 && len(f.tasks) == 0 {
+		f.cond.Broadcast()
+	}
+}
@@ -0,0 +1,217 @@
+package downloader
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"log"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxNameLength keeps file names under the 255 byte limit of most file
+// systems, with room for the extension added by -E and for ".part".
+const maxNameLength = 200
+
+// unsafeChars are escaped in file names: the separators, the characters
+// Windows does not allow, and "%" itself so that escaping stays reversible.
+const unsafeChars = `/\<>:"|?*%`
+
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// urlToLocalPath returns the path of a URL relative to the output directory:
+// its host and path segments, with "index.html" for directories and the
+// query string kept in the file name. Every segment goes through safeName,
+// so the path never leaves the directory of its host.
+func (d *Downloader) urlToLocalPath(u *url.URL) string {
+	// Split the escaped path so that an escaped "/" stays in its segment.
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, segment := range segments {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segments[i] = unescaped
+		}
+	}
+	file := segments[len(segments)-1]
+	if file == "" {
+		file = "index.html"
+	}
+	if u.RawQuery != "" {
+		file += "?" + u.RawQuery
+	}
+
+	var parts []string
+	if u.Host != "" {
+		parts = append(parts, safeName(strings.ToLower(u.Host)))
+	}
+	for _, segment := range segments[:len(segments)-1] {
+		if segment != "" {
+			parts = append(parts, safeName(segment))
+		}
+	}
+	parts = append(parts, safeName(file))
+
+	return filepath.Join(parts...)
+}
+
+// safeName escapes a path segment as %XX where it would not be a portable
+// file name: unsafe and control characters, "." and "..", trailing dots and
+// spaces, and the device names of Windows. Overlong names are shortened
+// with a hash of the full name.
+func safeName(name string) string {
+	if name == "." || name == ".." {
+		return strings.ReplaceAll(name, ".", "%2E")
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c < 0x20 || c == 0x7f || strings.IndexByte(unsafeChars, c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	res := b.String()
+
+	if last := res[len(res)-1]; last == '.' || last == ' ' {
+		res = fmt.Sprintf("%s%%%02X", res[:len(res)-1], last)
+	}
+	stem, _, _ := strings.Cut(res, ".")
+	if reservedNames[strings.ToUpper(stem)] {
+		res = fmt.Sprintf("%%%02X%s", res[0], res[1:])
+	}
+
+	if len(res) > maxNameLength {
+		ext := filepath.Ext(res)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		sum := sha1.Sum([]byte(res))
+		res = fmt.Sprintf("%s-%x%s", res[:maxNameLength-len(ext)-17], sum[:8], ext)
+	}
+	return res
+}
+
+// withExtension adds ".html" or ".css" to the path of a page or stylesheet
+// that lacks it, like wget -E.
+func withExtension(localPath, contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	var extensions []string
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		extensions = []string{".html", ".htm"}
+	case "text/css":
+		extensions = []string{".css"}
+	default:
+		return localPath
+	}
+
+	ext := strings.ToLower(filepath.Ext(localPath))
+	for _, e := range extensions {
+		if ext == e {
+			return localPath
+		}
+	}
+	return localPath + extensions[0]
+}
+
+// claimPath reserves a local path for the URL with the given key and
+// creates its directories. A file standing where a directory is needed is
+// moved into that directory as its index, a path that is already a
+// directory gets an index inside it, and a path taken by another URL gets
+// a numbered name.
+func (d *Downloader) claimPath(key, localPath string) (string, error) {
+	d.pathsMutex.Lock()
+	defer d.pathsMutex.Unlock()
+
+	dir := filepath.Dir(localPath)
+	parts := strings.Split(dir, string(filepath.Separator))
+	for i := range parts {
+		ancestor := filepath.Join(parts[:i+1]...)
+		info, err := os.Stat(d.fullPath(ancestor))
+		if err == nil && !info.IsDir() {
+			if err = d.moveIntoDir(ancestor); err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := os.MkdirAll(d.fullPath(dir), 0755); err != nil {
+		return "", err
+	}
+
+	if info, err := os.Stat(d.fullPath(localPath)); err == nil && info.IsDir() {
+		localPath = filepath.Join(localPath, "index.html")
+	}
+	localPath = d.uniquePath(key, localPath)
+	d.paths[localPath] = key
+	return localPath, nil
+}
+
+// placeFile moves the finished download of a claimed path into place. If a
+// directory was created there in the meantime, the file becomes its index.
+func (d *Downloader) placeFile(key, localPath string) (string, error) {
+	d.pathsMutex.Lock()
+	defer d.pathsMutex.Unlock()
+
+	partPath := d.fullPath(localPath) + ".part"
+	if info, err := os.Stat(d.fullPath(localPath)); err == nil && info.IsDir() {
+		delete(d.paths, localPath)
+		localPath = d.uniquePath(key, filepath.Join(localPath, "index.html"))
+		d.paths[localPath] = key
+	}
+	return localPath, os.Rename(partPath, d.fullPath(localPath))
+}
+
+// moveIntoDir turns the file at localPath into the index of a directory of
+// the same name.
+func (d *Downloader) moveIntoDir(localPath string) error {
+	key := d.paths[localPath]
+	target := d.uniquePath(key, filepath.Join(localPath, "index.html"))
+
+	tmp := d.fullPath(localPath) + ".moving"
+	if err := os.Rename(d.fullPath(localPath), tmp); err != nil {
+		return err
+	}
+	if err := os.Mkdir(d.fullPath(localPath), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, d.fullPath(target)); err != nil {
+		return err
+	}
+
+	delete(d.paths, localPath)
+	d.paths[target] = key
+	d.state.relocate(localPath, target)
+	d.relocateSaved(localPath, target)
+	log.Printf("Moved %s to %s", localPath, target)
+	return nil
+}
+
+// uniquePath returns localPath, or a numbered variant of it if another URL
+// already uses it. The caller holds pathsMutex.
+func (d *Downloader) uniquePath(key, localPath string) string {
+	ext := filepath.Ext(localPath)
+	stem := strings.TrimSuffix(localPath, ext)
+
+	path := localPath
+	for i := 1; ; i++ {
+		owner, ok := d.paths[path]
+		if !ok || owner == key {
+			return path
+		}
+		path = fmt.Sprintf("%s-%d%s", stem, i, ext)
+	}
+}
+
+func (d *Downloader) fullPath(localPath string) string {
+	return filepath.Join(d.config.OutputDir, localPath)
+}
@@ -0,0 +1,181 @@
+package downloader
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLToLocalPath(t *testing.T) {
+	long := strings.Repeat("x", 300)
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://Example.com", "example.com/index.html"},
+		{"http://example.com/", "example.com/index.html"},
+		{"http://example.com/a/b/", "example.com/a/b/index.html"},
+		{"http://example.com/a/b", "example.com/a/b"},
+		{"http://example.com//double//slash", "example.com/double/slash"},
+		{"http://example.com/list?page=1", "example.com/list%3Fpage=1"},
+		{"http://example.com/list?page=2", "example.com/list%3Fpage=2"},
+		{"http://example.com/?q=a/b", "example.com/index.html%3Fq=a%2Fb"},
+		{"http://example.com/a/../../etc/passwd", "example.com/a/%2E%2E/%2E%2E/etc/passwd"},
+		{"http://example.com/%2e%2e/%2E%2E/secret", "example.com/%2E%2E/%2E%2E/secret"},
+		{"http://example.com/./x", "example.com/%2E/x"},
+		{"http://example.com/a%2Fb", "example.com/a%2Fb"},
+		{"http://example.com/a%5Cb", "example.com/a%5Cb"},
+		{`http://example.com/a:b"c|d<e>f*g`, "example.com/a%3Ab%22c%7Cd%3Ce%3Ef%2Ag"},
+		{"http://example.com/100%25", "example.com/100%25"},
+		{"http://example.com/tab%09name", "example.com/tab%09name"},
+		{"http://example.com/trailing.", "example.com/trailing%2E"},
+		{"http://example.com/trailing%20", "example.com/trailing%20"},
+		{"http://example.com/con.txt", "example.com/%63on.txt"},
+		{"http://example.com/LPT1/x", "example.com/%4CPT1/x"},
+		{"http://example.com/console", "example.com/console"},
+		{"http://127.0.0.1:8080/x", "127.0.0.1%3A8080/x"},
+		{"http://example.com/caf%C3%A9", "example.com/café"},
+	}
+
+	d := NewDownloader(&Config{})
+	for _, test := range tests {
+		u, err := url.Parse(test.url)
+		require.NoError(t, err, test.url)
+		assert.Equal(t, filepath.FromSlash(test.want), d.urlToLocalPath(u), test.url)
+	}
+
+	u, err := url.Parse("http://example.com/" + long + ".html?" + long)
+	require.NoError(t, err)
+	name := filepath.Base(d.urlToLocalPath(u))
+	assert.Len(t, name, maxNameLength, "long names are shortened")
+	assert.True(t, strings.HasPrefix(name, "xxx"), name)
+
+	other, err := url.Parse("http://example.com/" + long + ".html?" + long + "y")
+	require.NoError(t, err)
+	assert.NotEqual(t, name, filepath.Base(d.urlToLocalPath(other)), "shortened names stay distinct")
+}
+
+func TestWithExtension(t *testing.T) {
+	tests := []struct {
+		path        string
+		contentType string
+		want        string
+	}{
+		{"a", "text/html", "a.html"},
+		{"a", "text/html; charset=utf-8", "a.html"},
+		{"a.html", "text/html", "a.html"},
+		{"a.HTM", "text/html", "a.HTM"},
+		{"a.php", "text/html", "a.php.html"},
+		{"list%3Fpage=2", "text/html", "list%3Fpage=2.html"},
+		{"a", "application/xhtml+xml", "a.html"},
+		{"style", "text/css", "style.css"},
+		{"style.css", "text/css", "style.css"},
+		{"image", "image/png", "image"},
+		{"a", "", "a"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, withExtension(test.path, test.contentType), "%s %s", test.path, test.contentType)
+	}
+}
+
+func TestDownloaderNaming(t *testing.T) {
+	site := map[string]string{
+		"/":     `<a href="a">a</a> <a href="a/b">b</a> <a href="a/">dir</a> <a href="page?x=1">1</a> <a href="page?x=2">2</a>`,
+		"/a":    `file a`,
+		"/a/b":  `<a href="../page?x=2">x2</a>`,
+		"/a/":   `dir a`,
+		"/page": `page`,
+	}
+
+	tests := []struct {
+		adjustExtension bool
+		files           map[string]string
+	}{
+		{false, map[string]string{
+			"index.html":     `<a href="a/index.html">a</a> <a href="a/b">b</a> <a href="a/index-1.html">dir</a> <a href="page%253Fx=1">1</a> <a href="page%253Fx=2">2</a>`,
+			"a/index.html":   site["/a"],
+			"a/b":            `<a href="../page%253Fx=2">x2</a>`,
+			"a/index-1.html": site["/a/"],
+			"page%3Fx=1":     site["/page"],
+			"page%3Fx=2":     site["/page"],
+		}},
+		{true, map[string]string{
+			"index.html":      `<a href="a.html">a</a> <a href="a/b.html">b</a> <a href="a/index.html">dir</a> <a href="page%253Fx=1.html">1</a> <a href="page%253Fx=2.html">2</a>`,
+			"a.html":          site["/a"],
+			"a/b.html":        `<a href="../page%253Fx=2.html">x2</a>`,
+			"a/index.html":    site["/a/"],
+			"page%3Fx=1.html": site["/page"],
+			"page%3Fx=2.html": site["/page"],
+		}},
+	}
+
+	for _, test := range tests {
+		server := newTestServer(t, site)
+		output := t.TempDir()
+
+		d := NewDownloader(&Config{
+			BaseURL:         server.URL + "/",
+			OutputDir:       output,
+			MaxDepth:        2,
+			Concurrency:     1,
+			Timeout:         5 * time.Second,
+			UserAgent:       "WebMirror/1.0",
+			ConvertLinks:    true,
+			AdjustExtension: test.adjustExtension,
+		})
+		require.NoError(t, d.Start())
+
+		for path, want := range test.files {
+			content, err := os.ReadFile(filepath.Join(output, hostDir(server.URL), filepath.FromSlash(path)))
+			if assert.NoError(t, err, "-E %v: %s", test.adjustExtension, path) {
+				assert.Equal(t, want, string(content), "-E %v: %s", test.adjustExtension, path)
+			}
+		}
+	}
+}
+
+func TestDownloaderDirectoryIndex(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/":               `<a href="index.html">home</a> <a href="sub/">sub</a> <a href="sub/index.html#top">sub</a> <a href="myindex.html">my</a>`,
+		"/index.html":     `same as /`,
+		"/sub/":           `<a href="/">home</a> <a href="../index.html">home</a>`,
+		"/sub/index.html": `same as /sub/`,
+		"/myindex.html":   `my`,
+	})
+	output := t.TempDir()
+
+	require.NoError(t, NewDownloader(&Config{
+		BaseURL:      server.URL + "/",
+		OutputDir:    output,
+		MaxDepth:     2,
+		Concurrency:  2,
+		Timeout:      5 * time.Second,
+		UserAgent:    "WebMirror/1.0",
+		ConvertLinks: true,
+	}).Start())
+
+	assert.ElementsMatch(t, []string{"/", "/sub/", "/myindex.html"}, server.requests)
+
+	files := map[string]string{
+		"index.html":     `<a href="index.html">home</a> <a href="sub/index.html">sub</a> <a href="sub/index.html#top">sub</a> <a href="myindex.html">my</a>`,
+		"sub/index.html": `<a href="../index.html">home</a> <a href="../index.html">home</a>`,
+		"myindex.html":   `my`,
+	}
+	root := filepath.Join(output, hostDir(server.URL))
+	for path, want := range files {
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(path)))
+		if assert.NoError(t, err, path) {
+			assert.Equal(t, want, string(content), path)
+		}
+	}
+	assert.NoFileExists(t, filepath.Join(root, "index-1.html"))
+	assert.NoFileExists(t, filepath.Join(root, "sub", "index-1.html"))
+}
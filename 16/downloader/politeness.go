@@ -0,0 +1,206 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRetryDelay = time.Second
+	maxRetryDelay     = time.Minute
+
+	// maxRetryAfter is the longest Retry-After honored. A request asked to
+	// wait longer fails instead of holding up a worker.
+	maxRetryAfter = 5 * time.Minute
+)
+
+// httpError is an unexpected response status, with the delay the server
+// asked for in Retry-After, if any.
+type httpError struct {
+	status     int
+	retryAfter time.Duration
+}
+
+func (e *httpError) Error() string {
+	if e.retryAfter > maxRetryAfter {
+		return fmt.Sprintf("HTTP %d, Retry-After %v too long", e.status, e.retryAfter)
+	}
+	return fmt.Sprintf("HTTP %d", e.status)
+}
+
+// retryable reports whether a failed download may succeed later: network
+// errors, 429 Too Many Requests and server errors, but not local file errors
+// or responses asking to wait longer than maxRetryAfter.
+func retryable(err error) bool {
+	var httpErr *httpError
+	if errors.As(err, &httpErr) {
+		if httpErr.retryAfter > maxRetryAfter {
+			return false
+		}
+		return httpErr.status == http.StatusTooManyRequests || httpErr.status >= 500
+	}
+	var pathErr *os.PathError
+	var linkErr *os.LinkError
+	return !errors.As(err, &pathErr) && !errors.As(err, &linkErr)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as a date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+// backoff returns the delay before retry number attempt, doubling from base
+// up to maxRetryDelay, with jitter over its upper half so that workers do
+// not retry in step.
+func backoff(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 0; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxRetryDelay)
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// downloadWithRetry calls download until it succeeds, fails permanently or
+// runs out of retries. After a Retry-After the limiter of the host already
+// holds the next request back, otherwise it backs off exponentially.
+func (d *Downloader) downloadWithRetry(urlStr string) (string, string, error) {
+	base := d.config.RetryDelay
+	if base <= 0 {
+		base = defaultRetryDelay
+	}
+
+	for attempt := 0; ; attempt++ {
+		localPath, contentType, err := d.download(urlStr)
+		if err == nil || attempt >= d.config.Retries || !retryable(err) {
+			return localPath, contentType, err
+		}
+
+		var httpErr *httpError
+		if errors.As(err, &httpErr) && httpErr.retryAfter > 0 {
+			log.Printf("Retrying %s after %v: %v", urlStr, httpErr.retryAfter, err)
+			continue
+		}
+		delay := backoff(base, attempt)
+		log.Printf("Retrying %s in %v: %v", urlStr, delay.Round(time.Millisecond), err)
+		time.Sleep(delay)
+	}
+}
+
+// hostLimiter spaces the requests to one host and caps the rate at which
+// their responses are read.
+type hostLimiter struct {
+	interval  time.Duration
+	bandwidth int64
+	next      time.Time
+	nextByte  time.Time
+	mutex     sync.Mutex
+}
+
+// limiter returns the limiter of the host of u. Requests are also spaced by
+// the Crawl-delay of the robots.txt of the host.
+func (d *Downloader) limiter(u *url.URL) *hostLimiter {
+	var crawlDelay time.Duration
+	if d.config.RespectRobots {
+		crawlDelay = d.robotsTxt(u).CrawlDelay()
+	}
+
+	d.limitersMutex.Lock()
+	defer d.limitersMutex.Unlock()
+
+	if l, ok := d.limiters[u.Host]; ok {
+		return l
+	}
+
+	l := &hostLimiter{bandwidth: d.config.BandwidthLimit}
+	if d.config.RateLimit > 0 {
+		l.interval = time.Duration(float64(time.Second) / d.config.RateLimit)
+	}
+	l.interval = max(l.interval, crawlDelay)
+	d.limiters[u.Host] = l
+	return l
+}
+
+// wait blocks until the next request to the host may start.
+func (l *hostLimiter) wait() {
+	l.mutex.Lock()
+	now := time.Now()
+	start := now
+	if l.next.After(start) {
+		start = l.next
+	}
+	l.next = start.Add(l.interval)
+	l.mutex.Unlock()
+
+	time.Sleep(start.Sub(now))
+}
+
+// pause delays the requests to the host that did not start yet.
+func (l *hostLimiter) pause(delay time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if until := time.Now().Add(delay); until.After(l.next) {
+		l.next = until
+	}
+}
+
+// consume accounts for n bytes read from the host and sleeps long enough to
+// keep the bandwidth under the limit.
+func (l *hostLimiter) consume(n int) {
+	if l.bandwidth <= 0 || n <= 0 {
+		return
+	}
+
+	l.mutex.Lock()
+	now := time.Now()
+	if l.nextByte.Before(now) {
+		l.nextByte = now
+	}
+	l.nextByte = l.nextByte.Add(time.Duration(float64(n) / float64(l.bandwidth) * float64(time.Second)))
+	until := l.nextByte
+	l.mutex.Unlock()
+
+	time.Sleep(until.Sub(now))
+}
+
+// reader wraps the body of a response from the host in its bandwidth limit.
+func (l *hostLimiter) reader(r io.Reader) io.Reader {
+	if l.bandwidth <= 0 {
+		return r
+	}
+	return &throttledReader{reader: r, limiter: l}
+}
+
+type throttledReader struct {
+	reader  io.Reader
+	limiter *hostLimiter
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	// Small reads keep each sleep short, so the transfer stays smooth.
+	if limit := max(r.limiter.bandwidth, 512); int64(len(p)) > limit {
+		p = p[:limit]
+	}
+	n, err := r.reader.Read(p)
+	r.limiter.consume(n)
+	return n, err
+}
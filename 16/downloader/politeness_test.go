@@ -0,0 +1,281 @@
+package downloader
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"0", 0},
+		{"120", 2 * time.Minute},
+		{"-5", 0},
+		{"Mon, 01 Jan 2024 00:00:30 GMT", 30 * time.Second},
+		{"Sun, 31 Dec 2023 23:00:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, parseRetryAfter(test.value, now), test.value)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		low     time.Duration
+		high    time.Duration
+	}{
+		{0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{1, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond, 800 * time.Millisecond},
+		{20, maxRetryDelay / 2, maxRetryDelay},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 20; i++ {
+			delay := backoff(100*time.Millisecond, test.attempt)
+			assert.GreaterOrEqual(t, delay, test.low, "attempt %d", test.attempt)
+			assert.LessOrEqual(t, delay, test.high, "attempt %d", test.attempt)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&httpError{status: http.StatusTooManyRequests}, true},
+		{&httpError{status: http.StatusServiceUnavailable}, true},
+		{&httpError{status: http.StatusInternalServerError}, true},
+		{&httpError{status: http.StatusServiceUnavailable, retryAfter: maxRetryAfter}, true},
+		{&httpError{status: http.StatusServiceUnavailable, retryAfter: 24 * time.Hour}, false},
+		{&httpError{status: http.StatusNotFound}, false},
+		{&httpError{status: http.StatusForbidden}, false},
+		{errors.New("connection reset by peer"), true},
+		{&os.PathError{Op: "open", Path: "x", Err: os.ErrPermission}, false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, retryable(test.err), test.err.Error())
+	}
+}
+
+// flakyServer fails each path as many times as given in failures, with the
+// status and Retry-After header of the path.
+type flakyServer struct {
+	*httptest.Server
+	attempts map[string][]time.Time
+	mutex    sync.Mutex
+}
+
+func newFlakyServer(t *testing.T, site map[string]string, failures map[string]int, status map[string]int, retryAfter string) *flakyServer {
+	server := &flakyServer{attempts: make(map[string][]time.Time)}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		server.attempts[r.URL.Path] = append(server.attempts[r.URL.Path], time.Now())
+		attempt := len(server.attempts[r.URL.Path])
+		server.mutex.Unlock()
+
+		body, ok := site[r.URL.Path]
+		switch {
+		case !ok:
+			http.NotFound(w, r)
+		case attempt <= failures[r.URL.Path]:
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status[r.URL.Path])
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(body))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDownloaderRetries(t *testing.T) {
+	site := map[string]string{
+		"/":           `<a href="flaky.html">f</a> <a href="busy.html">b</a> <a href="gone.html">g</a> <a href="down.html">d</a>`,
+		"/flaky.html": "flaky",
+		"/busy.html":  "busy",
+		"/gone.html":  "gone",
+		"/down.html":  "down",
+	}
+	failures := map[string]int{"/flaky.html": 2, "/busy.html": 1, "/gone.html": 5, "/down.html": 5}
+	status := map[string]int{
+		"/flaky.html": http.StatusBadGateway,
+		"/busy.html":  http.StatusTooManyRequests,
+		"/gone.html":  http.StatusGone,
+		"/down.html":  http.StatusServiceUnavailable,
+	}
+
+	tests := []struct {
+		retries  int
+		attempts map[string]int
+		saved    []string
+	}{
+		{0, map[string]int{"/": 1, "/flaky.html": 1, "/busy.html": 1, "/gone.html": 1, "/down.html": 1}, nil},
+		{3, map[string]int{"/": 1, "/flaky.html": 3, "/busy.html": 2, "/gone.html": 1, "/down.html": 4}, []string{"flaky.html", "busy.html"}},
+	}
+
+	for _, test := range tests {
+		server := newFlakyServer(t, site, failures, status, "")
+		output := t.TempDir()
+
+		d := NewDownloader(&Config{
+			BaseURL:     server.URL + "/",
+			OutputDir:   output,
+			MaxDepth:    1,
+			Concurrency: 4,
+			Timeout:     5 * time.Second,
+			UserAgent:   "WebMirror/1.0",
+			Retries:     test.retries,
+			RetryDelay:  10 * time.Millisecond,
+		})
+		require.NoError(t, d.Start())
+
+		for path, want := range test.attempts {
+			assert.Len(t, server.attempts[path], want, "retries %d: %s attempts", test.retries, path)
+		}
+		host := hostDir(server.URL)
+		for _, name := range []string{"flaky.html", "busy.html", "gone.html", "down.html"} {
+			_, err := os.Stat(filepath.Join(output, host, name))
+			assert.Equal(t, slices.Contains(test.saved, name), err == nil, "retries %d: %s saved", test.retries, name)
+		}
+	}
+}
+
+func TestDownloaderRetryAfter(t *testing.T) {
+	site := map[string]string{"/": "home"}
+	server := newFlakyServer(t, site, map[string]int{"/": 1}, map[string]int{"/": http.StatusServiceUnavailable}, "1")
+
+	d := NewDownloader(&Config{
+		BaseURL:     server.URL + "/",
+		OutputDir:   t.TempDir(),
+		Concurrency: 1,
+		Timeout:     5 * time.Second,
+		UserAgent:   "WebMirror/1.0",
+		Retries:     1,
+		RetryDelay:  time.Millisecond,
+	})
+	require.NoError(t, d.Start())
+
+	attempts := server.attempts["/"]
+	require.Len(t, attempts, 2)
+	assert.GreaterOrEqual(t, attempts[1].Sub(attempts[0]), time.Second, "Retry-After is honored")
+}
+
+func TestDownloaderRetryAfterTooLong(t *testing.T) {
+	site := map[string]string{"/": "home"}
+	server := newFlakyServer(t, site, map[string]int{"/": 1}, map[string]int{"/": http.StatusServiceUnavailable}, "86400")
+
+	d := NewDownloader(&Config{
+		BaseURL:     server.URL + "/",
+		OutputDir:   t.TempDir(),
+		Concurrency: 1,
+		Timeout:     5 * time.Second,
+		UserAgent:   "WebMirror/1.0",
+		Retries:     3,
+		RetryDelay:  time.Millisecond,
+	})
+
+	start := time.Now()
+	require.NoError(t, d.Start())
+	assert.Less(t, time.Since(start), time.Second, "a day-long Retry-After fails at once")
+	assert.Len(t, server.attempts["/"], 1)
+}
+
+func TestDownloaderRateLimit(t *testing.T) {
+	site := map[string]string{
+		"/":       `<a href="1.html">1</a> <a href="2.html">2</a> <a href="3.html">3</a> <a href="4.html">4</a>`,
+		"/1.html": "1",
+		"/2.html": "2",
+		"/3.html": "3",
+		"/4.html": "4",
+	}
+
+	tests := []struct {
+		rateLimit  float64
+		crawlDelay string
+		minGap     time.Duration
+	}{
+		{20, "", 50 * time.Millisecond},
+		{0, "0.1", 100 * time.Millisecond},
+		{100, "0.05", 50 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		if test.crawlDelay != "" {
+			site["/robots.txt"] = "User-agent: *\nCrawl-delay: " + test.crawlDelay + "\n"
+		}
+		server := newFlakyServer(t, site, nil, nil, "")
+
+		d := NewDownloader(&Config{
+			BaseURL:       server.URL + "/",
+			OutputDir:     t.TempDir(),
+			MaxDepth:      1,
+			Concurrency:   4,
+			Timeout:       5 * time.Second,
+			UserAgent:     "WebMirror/1.0",
+			RespectRobots: test.crawlDelay != "",
+			RateLimit:     test.rateLimit,
+		})
+		require.NoError(t, d.Start())
+
+		var starts []time.Time
+		for path, attempts := range server.attempts {
+			if path != "/robots.txt" {
+				starts = append(starts, attempts...)
+			}
+		}
+		require.Len(t, starts, 5)
+		slices.SortFunc(starts, time.Time.Compare)
+		for i := 1; i < len(starts); i++ {
+			// Allow for the clock granularity of the server.
+			assert.GreaterOrEqual(t, starts[i].Sub(starts[i-1]), test.minGap-5*time.Millisecond, "rate %v, crawl delay %s", test.rateLimit, test.crawlDelay)
+		}
+	}
+}
+
+func TestDownloaderBandwidthLimit(t *testing.T) {
+	body := strings.Repeat("x", 32<<10)
+	server := newFlakyServer(t, map[string]string{"/": body}, nil, nil, "")
+	output := t.TempDir()
+
+	d := NewDownloader(&Config{
+		BaseURL:        server.URL + "/",
+		OutputDir:      output,
+		Concurrency:    1,
+		Timeout:        5 * time.Second,
+		UserAgent:      "WebMirror/1.0",
+		BandwidthLimit: 64 << 10,
+	})
+
+	start := time.Now()
+	require.NoError(t, d.Start())
+	assert.GreaterOrEqual(t, time.Since(start), 450*time.Millisecond, "32KiB at 64KiB/s")
+
+	content, err := os.ReadFile(filepath.Join(output, hostDir(server.URL), "index.html"))
+	require.NoError(t, err)
+	assert.Equal(t, body, string(content))
+}
@@ -0,0 +1,172 @@
+package downloader
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const stateFile = ".mirror-state.json"
+
+// crawlState is the record of the mirror kept in its output directory. Each
+// run has a number and every downloaded URL remembers the run that fetched
+// it, so a run that did not finish can be resumed without fetching those
+// URLs again, while later runs revalidate them with conditional requests.
+type crawlState struct {
+	Run      int                    `json:"run"`
+	Finished bool                   `json:"finished"`
+	Entries  map[string]*stateEntry `json:"entries"`
+
+	path      string
+	savedTime time.Time
+	mutex     sync.Mutex
+}
+
+type stateEntry struct {
+	URL          string `json:"url"`
+	Path         string `json:"path"`
+	ContentType  string `json:"content_type,omitempty"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Run          int    `json:"run"`
+	Partial      bool   `json:"partial,omitempty"`
+	Converted    bool   `json:"converted,omitempty"`
+}
+
+// loadCrawlState reads the state of the mirror in dir and starts a run.
+func loadCrawlState(dir string) (*crawlState, error) {
+	s := &crawlState{
+		Finished: true,
+		Entries:  make(map[string]*stateEntry),
+		path:     filepath.Join(dir, stateFile),
+	}
+
+	data, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err = json.Unmarshal(data, s); err != nil {
+			return nil, err
+		}
+		if s.Entries == nil {
+			s.Entries = make(map[string]*stateEntry)
+		}
+	}
+
+	if s.Finished {
+		s.Run++
+	} else {
+		log.Printf("Resuming unfinished run %d", s.Run)
+	}
+	s.Finished = false
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s, s.save()
+}
+
+func (s *crawlState) get(key string) (stateEntry, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, ok := s.Entries[key]
+	if !ok {
+		return stateEntry{}, false
+	}
+	return *entry, true
+}
+
+// put records an entry fetched or revalidated by the current run. The state
+// is written at most once a second while the crawl goes on.
+func (s *crawlState) put(key string, entry stateEntry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry.Run = s.Run
+	s.Entries[key] = &entry
+	if time.Since(s.savedTime) >= time.Second {
+		if err := s.save(); err != nil {
+			log.Printf("Failed to save crawl state: %v", err)
+		}
+	}
+}
+
+func (s *crawlState) remove(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.Entries, key)
+}
+
+// relocate records that the file at oldPath was moved to newPath.
+func (s *crawlState) relocate(oldPath, newPath string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, entry := range s.Entries {
+		if entry.Path == oldPath {
+			entry.Path = newPath
+		}
+	}
+}
+
+// paths returns the local path of every entry by its key.
+func (s *crawlState) paths() map[string]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	paths := make(map[string]string, len(s.Entries))
+	for key, entry := range s.Entries {
+		paths[entry.Path] = key
+	}
+	return paths
+}
+
+// markConverted records that the links of a file were rewritten, so its
+// content no longer matches the validators of the server.
+func (s *crawlState) markConverted(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entry, ok := s.Entries[key]; ok {
+		entry.Converted = true
+	}
+}
+
+// fresh reports whether the entry was completed by the current run before
+// it was interrupted.
+func (s *crawlState) fresh(entry stateEntry) bool {
+	return entry.Run == s.Run && !entry.Partial && !entry.Converted
+}
+
+func (s *crawlState) finish() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Finished = true
+	return s.save()
+}
+
+// save writes the state through a temporary file so an interrupted write
+// never leaves a truncated state behind. The caller holds the mutex.
+func (s *crawlState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.savedTime = time.Now()
+	return nil
+}
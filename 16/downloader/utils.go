@@ -1,25 +1,53 @@
 package downloader
 
 import (
+	"bufio"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type RobotsTxt struct {
-	rules map[string][]string
+	userAgent   string
+	rules       []robotsRule
+	disallowAll bool
+	crawlDelay  time.Duration
+	sitemaps    []string
 }
 
-func NewRobotsTxt() *RobotsTxt {
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+func NewRobotsTxt(userAgent string) *RobotsTxt {
 	return &RobotsTxt{
-		rules: make(map[string][]string),
+		userAgent: userAgent,
 	}
 }
 
+// Load fetches robots.txt from the host of baseURL. A missing file allows
+// everything, while a server error disallows everything until the next load.
 func (r *RobotsTxt) Load(client *http.Client, baseURL *url.URL) error {
 	robotsURL := baseURL.Scheme + "://" + baseURL.Host + "/robots.txt"
 
-	resp, err := client.Get(robotsURL)
+	req, err := http.NewRequest("GET", robotsURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", r.userAgent)
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -30,9 +58,174 @@ func (r *RobotsTxt) Load(client *http.Client, baseURL *url.URL) error {
 		}
 	}()
 
+	switch {
+	case resp.StatusCode >= 500:
+		r.rules = nil
+		r.disallowAll = true
+		return nil
+	case resp.StatusCode != http.StatusOK:
+		r.rules = nil
+		r.disallowAll = false
+		return nil
+	}
+
+	r.disallowAll = false
+	return r.Parse(resp.Body)
+}
+
+// Parse reads the rules of the group that matches the user agent most
+// specifically, falling back to the "*" group, and the sitemaps.
+func (r *RobotsTxt) Parse(reader io.Reader) error {
+	var groups []*robotsGroup
+	var current *robotsGroup
+	inAgents := false
+
+	r.sitemaps = nil
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+				inAgents = true
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			continue
+		case "sitemap":
+			if value != "" {
+				r.sitemaps = append(r.sitemaps, value)
+			}
+		case "allow", "disallow":
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{pattern: value, allow: key == "allow"})
+			}
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); current != nil && err == nil && seconds >= 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+		inAgents = false
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	r.rules = nil
+	r.crawlDelay = 0
+	for _, group := range r.matchingGroups(groups) {
+		r.rules = append(r.rules, group.rules...)
+		r.crawlDelay = max(r.crawlDelay, group.crawlDelay)
+	}
 	return nil
 }
 
+// matchingGroups returns the groups naming the longest user agent that is
+// part of ours, or the "*" groups if none does.
+func (r *RobotsTxt) matchingGroups(groups []*robotsGroup) []*robotsGroup {
+	userAgent := strings.ToLower(r.userAgent)
+	product, _, _ := strings.Cut(userAgent, "/")
+
+	best := ""
+	var res, wildcard []*robotsGroup
+	for _, group := range groups {
+		for _, agent := range group.agents {
+			switch {
+			case agent == "*":
+				wildcard = append(wildcard, group)
+			case agent == product || strings.Contains(userAgent, agent):
+				if len(agent) > len(best) {
+					best = agent
+					res = nil
+				}
+				if agent == best {
+					res = append(res, group)
+				}
+			default:
+				continue
+			}
+			break
+		}
+	}
+
+	if res == nil {
+		return wildcard
+	}
+	return res
+}
+
+// Allowed reports whether urlStr may be fetched. The longest matching rule
+// decides, and Allow wins between rules of the same length.
 func (r *RobotsTxt) Allowed(urlStr string) bool {
-	return true
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return false
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+	if r.disallowAll {
+		return false
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	allowed := true
+	matched := -1
+	for _, rule := range r.rules {
+		if !matchRobotsPattern(rule.pattern, path) {
+			continue
+		}
+		if length := len(rule.pattern); length > matched || length == matched && rule.allow {
+			matched = length
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+func (r *RobotsTxt) CrawlDelay() time.Duration {
+	return r.crawlDelay
+}
+
+func (r *RobotsTxt) Sitemaps() []string {
+	return r.sitemaps
+}
+
+// matchRobotsPattern matches a path against a rule where "*" stands for any
+// characters and a trailing "$" anchors the end of the path.
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	parts := strings.Split(strings.TrimSuffix(pattern, "$"), "*")
+
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		index := strings.Index(rest, part)
+		if index < 0 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+	return !anchored || rest == ""
 }
@@ -0,0 +1,161 @@
+package downloader
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRobots = `# comment
+User-agent: *
+Disallow: /private/
+Allow: /private/public
+Disallow: /*.pdf$
+Disallow: /search?q=*
+Crawl-delay: 2
+
+User-agent: OtherBot
+User-agent: WebMirror
+Disallow: /
+Allow: /docs/
+Allow: /$
+Disallow: /docs/drafts
+Allow: /docs/drafts/ok.html
+Crawl-delay: 0.5
+
+User-agent: WebMirror-Images
+Disallow: /docs/
+
+Sitemap: https://example.com/sitemap.xml
+Sitemap: https://example.com/news.xml
+`
+
+func newRobotsServer(t *testing.T, status int, body string, agents *[]string) *url.URL {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if agents != nil {
+			*agents = append(*agents, r.UserAgent())
+		}
+		if r.URL.Path != "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	base, err := url.Parse(server.URL + "/start")
+	require.NoError(t, err)
+	return base
+}
+
+func TestRobotsTxtLoad(t *testing.T) {
+	tests := []struct {
+		userAgent  string
+		path       string
+		allowed    bool
+		crawlDelay time.Duration
+	}{
+		{"Mozilla/5.0", "/", true, 2 * time.Second},
+		{"Mozilla/5.0", "/private/secret.html", false, 2 * time.Second},
+		{"Mozilla/5.0", "/private/public/page.html", true, 2 * time.Second},
+		{"Mozilla/5.0", "/files/report.pdf", false, 2 * time.Second},
+		{"Mozilla/5.0", "/files/report.pdf?download=1", true, 2 * time.Second},
+		{"Mozilla/5.0", "/search?q=go", false, 2 * time.Second},
+		{"Mozilla/5.0", "/search", true, 2 * time.Second},
+		{"Mozilla/5.0", "/robots.txt", true, 2 * time.Second},
+		{"WebMirror/1.0", "/", true, 500 * time.Millisecond},
+		{"WebMirror/1.0", "/index.html", false, 500 * time.Millisecond},
+		{"WebMirror/1.0", "/private/secret.html", false, 500 * time.Millisecond},
+		{"WebMirror/1.0", "/docs/intro.html", true, 500 * time.Millisecond},
+		{"WebMirror/1.0", "/docs/drafts/new.html", false, 500 * time.Millisecond},
+		{"WebMirror/1.0", "/docs/drafts/ok.html", true, 500 * time.Millisecond},
+		{"webmirror/2.0 (+https://example.com)", "/docs/intro.html", true, 500 * time.Millisecond},
+		{"WebMirror-Images/1.0", "/docs/intro.html", false, 0},
+		{"WebMirror-Images/1.0", "/private/secret.html", true, 0},
+	}
+
+	for _, test := range tests {
+		var agents []string
+		base := newRobotsServer(t, http.StatusOK, testRobots, &agents)
+
+		robots := NewRobotsTxt(test.userAgent)
+		require.NoError(t, robots.Load(http.DefaultClient, base))
+
+		target := base.Scheme + "://" + base.Host + test.path
+		assert.Equal(t, test.allowed, robots.Allowed(target), "%s %s", test.userAgent, test.path)
+		assert.Equal(t, test.crawlDelay, robots.CrawlDelay(), "%s crawl delay", test.userAgent)
+		assert.Equal(t, []string{"https://example.com/sitemap.xml", "https://example.com/news.xml"}, robots.Sitemaps(), test.userAgent)
+		assert.Equal(t, []string{test.userAgent}, agents, "%s request user agent", test.userAgent)
+	}
+}
+
+func TestRobotsTxtStatus(t *testing.T) {
+	tests := []struct {
+		status  int
+		body    string
+		allowed bool
+	}{
+		{http.StatusOK, "User-agent: *\nDisallow: /\n", false},
+		{http.StatusOK, "User-agent: *\nDisallow:\n", true},
+		{http.StatusOK, "", true},
+		{http.StatusNotFound, "User-agent: *\nDisallow: /\n", true},
+		{http.StatusForbidden, "", true},
+		{http.StatusInternalServerError, "", false},
+		{http.StatusServiceUnavailable, "User-agent: *\nAllow: /\n", false},
+	}
+
+	for _, test := range tests {
+		base := newRobotsServer(t, test.status, test.body, nil)
+
+		robots := NewRobotsTxt("WebMirror/1.0")
+		require.NoError(t, robots.Load(http.DefaultClient, base))
+		assert.Equal(t, test.allowed, robots.Allowed(base.String()), "%d %q", test.status, test.body)
+	}
+}
+
+func TestMatchRobotsPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"/", "/anything", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish.html", false},
+		{"/fish/", "/fish", false},
+		{"/fish*", "/fishheads/yummy.html", true},
+		{"/*.php", "/folder/filename.php?parameters", true},
+		{"/*.php", "/windows.PHP", false},
+		{"/*.php$", "/filename.php", true},
+		{"/*.php$", "/filename.php?parameters", false},
+		{"/*.php$", "/filename.php5", false},
+		{"/fish*.php", "/fishheads/catfish.php?parameters", true},
+		{"/fish*.php", "/Fish.PHP", false},
+		{"/a*b*c$", "/axxbyyc", true},
+		{"/a*b*c$", "/axxbyycz", false},
+		{"/a*c$", "/acbc", true},
+		{"/$", "/", true},
+		{"/$", "/index.html", false},
+		{"*", "/", true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.match, matchRobotsPattern(test.pattern, test.path), "%s %s", test.pattern, test.path)
+	}
+}
+
+func TestRobotsTxtParse(t *testing.T) {
+	robots := NewRobotsTxt("WebMirror/1.0")
+	require.NoError(t, robots.Parse(strings.NewReader("Sitemap: /first.xml\nUSER-AGENT: webmirror\nDISALLOW: /x # trailing\n")))
+
+	assert.False(t, robots.Allowed("http://example.com/x/y"), "keys are case-insensitive")
+	assert.True(t, robots.Allowed("http://example.com/y"), "unmatched paths are allowed")
+	assert.Equal(t, []string{"/first.xml"}, robots.Sitemaps(), "sitemaps outside groups")
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"L2/16/downloader"
+	"errors"
 	"flag"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,6 +17,32 @@ func main() {
 	outputDir := flag.String("output", "mirrored_site", "Output directory")
 	concurrency := flag.Int("concurrency", 5, "Number of concurrent mirrors")
 	timeout := flag.Int("timeout", 30, "Request timeout")
+	order := flag.String("order", "bfs", "Crawl order: bfs or dfs")
+	convertLinks := flag.Bool("convert-links", false, "Rewrite links in saved pages for offline browsing")
+	rate := flag.Float64("rate", 0, "Requests per second to each host (0 for no limit)")
+	limitRate := flag.String("limit-rate", "", "Bandwidth per host in bytes per second, with an optional k or m suffix")
+	retries := flag.Int("retries", 3, "Retries of failed requests")
+	var adjustExtension bool
+	flag.BoolVar(&adjustExtension, "adjust-extension", false, "Save pages and stylesheets with an .html or .css extension")
+	flag.BoolVar(&adjustExtension, "E", false, "Shorthand for -adjust-extension")
+	domains := flag.String("domains", "", "Comma-separated domains to follow, with their subdomains")
+	excludeDomains := flag.String("exclude-domains", "", "Comma-separated domains never to follow")
+	var spanHosts, noParent bool
+	flag.BoolVar(&spanHosts, "span-hosts", false, "Fetch page requisites from any host")
+	flag.BoolVar(&spanHosts, "H", false, "Shorthand for -span-hosts")
+	flag.BoolVar(&noParent, "no-parent", false, "Do not ascend above the directory of the URL")
+	flag.BoolVar(&noParent, "np", false, "Shorthand for -no-parent")
+	var includeDirs, excludeDirs, accept, reject string
+	flag.StringVar(&includeDirs, "include-directories", "", "Comma-separated path prefixes to follow")
+	flag.StringVar(&includeDirs, "I", "", "Shorthand for -include-directories")
+	flag.StringVar(&excludeDirs, "exclude-directories", "", "Comma-separated path prefixes to skip")
+	flag.StringVar(&excludeDirs, "X", "", "Shorthand for -exclude-directories")
+	flag.StringVar(&accept, "accept", "", "Comma-separated suffixes or patterns of file names to keep")
+	flag.StringVar(&accept, "A", "", "Shorthand for -accept")
+	flag.StringVar(&reject, "reject", "", "Comma-separated suffixes or patterns of file names to skip")
+	flag.StringVar(&reject, "R", "", "Shorthand for -reject")
+	acceptRegex := flag.String("accept-regex", "", "Regular expression the URLs to fetch must match")
+	rejectRegex := flag.String("reject-regex", "", "Regular expression of URLs not to fetch")
 
 	flag.Parse()
 
@@ -21,19 +50,49 @@ func main() {
 		log.Fatal("Missing URL")
 	}
 
-	err := os.MkdirAll(*outputDir, 0755)
+	crawlOrder := downloader.BreadthFirst
+	switch *order {
+	case "bfs":
+	case "dfs":
+		crawlOrder = downloader.DepthFirst
+	default:
+		log.Fatal("Unknown crawl order: ", *order)
+	}
+
+	bandwidth, err := parseRate(*limitRate)
+	if err != nil {
+		log.Fatal("Invalid -limit-rate: ", err)
+	}
+
+	err = os.MkdirAll(*outputDir, 0755)
 	if err != nil {
 		log.Fatal("Failed to create output directory")
 	}
 
 	dl := downloader.NewDownloader(&downloader.Config{
-		BaseURL:       *url,
-		OutputDir:     *outputDir,
-		MaxDepth:      *depth,
-		Concurrency:   *concurrency,
-		Timeout:       time.Duration(*timeout) * time.Second,
-		UserAgent:     "WebMirror/1.0",
-		RespectRobots: true,
+		BaseURL:         *url,
+		OutputDir:       *outputDir,
+		MaxDepth:        *depth,
+		Concurrency:     *concurrency,
+		Timeout:         time.Duration(*timeout) * time.Second,
+		UserAgent:       "WebMirror/1.0",
+		RespectRobots:   true,
+		Order:           crawlOrder,
+		ConvertLinks:    *convertLinks,
+		AdjustExtension: adjustExtension,
+		RateLimit:       *rate,
+		BandwidthLimit:  bandwidth,
+		Retries:         *retries,
+		Domains:         splitList(*domains),
+		ExcludeDomains:  splitList(*excludeDomains),
+		SpanHosts:       spanHosts,
+		IncludeDirs:     splitList(includeDirs),
+		ExcludeDirs:     splitList(excludeDirs),
+		NoParent:        noParent,
+		Accept:          splitList(accept),
+		Reject:          splitList(reject),
+		AcceptRegex:     *acceptRegex,
+		RejectRegex:     *rejectRegex,
 	})
 
 	err = dl.Start()
@@ -41,3 +100,40 @@ func main() {
 		log.Fatal("Mirroring failed:", err)
 	}
 }
+
+// parseRate reads a byte rate such as "500", "20k" or "1.5m".
+func parseRate(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	multiplier := 1.0
+	switch strings.ToLower(s[len(s)-1:]) {
+	case "k":
+		multiplier = 1 << 10
+		s = s[:len(s)-1]
+	case "m":
+		multiplier = 1 << 20
+		s = s[:len(s)-1]
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if value < 0 {
+		return 0, errors.New("negative rate")
+	}
+	return int64(value * multiplier), nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -4,50 +4,33 @@ import (
 	"net/url"
 	"path/filepath"
 	"strings"
-
-	"golang.org/x/net/html"
 )
 
-func ExtractLinks(content []byte, baseURL *url.URL) []string {
-	var links []string
-	visited := make(map[string]bool)
-
-	doc, err := html.Parse(strings.NewReader(string(content)))
-	if err != nil {
-		return links
-	}
+// ExtractLinks returns the links of an HTML page, including the resources
+// referenced from inline styles.
+func ExtractLinks(content []byte, baseURL *url.URL) []Link {
+	var links []Link
+	walkHTML(content, baseURL, collectLinks(&links))
+	return links
+}
 
-	var extract func(*html.Node)
-	extract = func(n *html.Node) {
-		if n.Type == html.ElementNode {
-			var attr string
-			switch n.Data {
-			case "a", "link":
-				attr = "href"
-			case "img", "script", "iframe":
-				attr = "src"
-			case "source":
-				attr = "srcset"
-			}
+// ExtractCSSLinks returns the resources imported or referenced by url() in
+// a stylesheet.
+func ExtractCSSLinks(content []byte, baseURL *url.URL) []Link {
+	var links []Link
+	walkCSS(content, baseURL, collectLinks(&links))
+	return links
+}
 
-			if attr != "" {
-				for _, a := range n.Attr {
-					if a.Key == attr {
-						absoluteURL := resolveURL(a.Val, baseURL)
-						if absoluteURL != "" && !visited[absoluteURL] {
-							links = append(links, absoluteURL)
-							visited[absoluteURL] = true
-						}
-					}
-				}
-			}
-			for c := n.FirstChild; c != nil; c = c.NextSibling {
-				extract(c)
-			}
+func collectLinks(links *[]Link) func(Link) string {
+	visited := make(map[string]bool)
+	return func(link Link) string {
+		if !visited[link.URL] {
+			*links = append(*links, link)
+			visited[link.URL] = true
 		}
+		return link.URL
 	}
-	extract(doc)
-	return links
 }
 
 func resolveURL(link string, baseURL *url.URL) string {
@@ -81,5 +64,5 @@ func IsPageURL(urlStr string) bool {
 		".mov": true, ".wav": true,
 	}
 
-	return !resourceExts[ext] && !strings.Contains(path, ".")
+	return !resourceExts[ext]
 }
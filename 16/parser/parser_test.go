@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractLinks(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []Link
+	}{
+		{`<a href="page.html">p</a><area href="/map">`, []Link{{"http://example.com/dir/page.html", false}, {"http://example.com/map", false}}},
+		{`<link rel="stylesheet" href="s.css"><link rel="next" href="2.html"><link rel="Shortcut Icon" href="/favicon.ico">`, []Link{{"http://example.com/dir/s.css", true}, {"http://example.com/dir/2.html", false}, {"http://example.com/favicon.ico", true}}},
+		{`<img src="a.png" srcset="a.png 1x, b@2x.png 2x">`, []Link{{"http://example.com/dir/a.png", true}, {"http://example.com/dir/b@2x.png", true}}},
+		{`<picture><source srcset="w.webp 480w, l.webp 800w"><img src="f.jpg"></picture>`, []Link{{"http://example.com/dir/w.webp", true}, {"http://example.com/dir/l.webp", true}, {"http://example.com/dir/f.jpg", true}}},
+		{`<video src="v.mp4" poster="p.jpg"><source src="v.webm"><track src="c.vtt"></video><audio src="/a.mp3"></audio>`, []Link{{"http://example.com/dir/v.mp4", true}, {"http://example.com/dir/p.jpg", true}, {"http://example.com/dir/v.webm", true}, {"http://example.com/dir/c.vtt", true}, {"http://example.com/a.mp3", true}}},
+		{`<object data="movie.swf"></object><embed src="e.swf"><iframe src="frame.html"></iframe>`, []Link{{"http://example.com/dir/movie.swf", true}, {"http://example.com/dir/e.swf", true}, {"http://example.com/dir/frame.html", true}}},
+		{`<style>@import "print.css"; body { background: url(bg.png) }</style>`, []Link{{"http://example.com/dir/bg.png", true}, {"http://example.com/dir/print.css", true}}},
+		{`<div style="background-image: url('/img/hero.jpg')">x</div>`, []Link{{"http://example.com/img/hero.jpg", true}}},
+		{`<meta http-equiv="Refresh" content="5; url=next.html"><meta http-equiv="refresh" content="10">`, []Link{{"http://example.com/dir/next.html", false}}},
+		{`<head><base href="http://cdn.example.org/assets/"></head><img src="logo.png"><a href="/top">t</a>`, []Link{{"http://cdn.example.org/assets/logo.png", true}, {"http://cdn.example.org/top", false}}},
+		{`<a href="#frag">f</a><a href="javascript:void(0)">j</a><img src="data:image/gif;base64,R0lG"><a href="ftp://example.com/f">f</a>`, nil},
+		{`<img src="a.png"><img src="a.png">`, []Link{{"http://example.com/dir/a.png", true}}},
+	}
+
+	base, err := url.Parse("http://example.com/dir/index.html")
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, ExtractLinks([]byte(test.content), base), test.content)
+	}
+}
+
+func TestExtractCSSLinks(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []Link
+	}{
+		{`@import url("fonts.css"); @import 'print.css' print;`, []Link{{"http://example.com/css/fonts.css", true}, {"http://example.com/css/print.css", true}}},
+		{`@font-face { src: url(../fonts/a.woff2) format("woff2"), url(../fonts/a.woff) format("woff") }`, []Link{{"http://example.com/fonts/a.woff2", true}, {"http://example.com/fonts/a.woff", true}}},
+		{`a { background: url( "bg.png" ) no-repeat } b { background: url(bg.png) }`, []Link{{"http://example.com/css/bg.png", true}}},
+		{`a { background: url(data:image/png;base64,AAAA) }`, nil},
+	}
+
+	base, err := url.Parse("http://example.com/css/style.css")
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, ExtractCSSLinks([]byte(test.content), base), test.content)
+	}
+}
@@ -0,0 +1,257 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Link is a URL found in a page or stylesheet. Requisites are the files
+// needed to display the page, such as images, scripts, stylesheets and
+// fonts, rather than pages it links to.
+type Link struct {
+	URL       string
+	Requisite bool
+}
+
+// linkAttrs lists the attributes holding links for each element.
+var linkAttrs = map[string][]string{
+	"a":      {"href"},
+	"area":   {"href"},
+	"link":   {"href"},
+	"img":    {"src", "srcset"},
+	"input":  {"src"},
+	"script": {"src"},
+	"iframe": {"src"},
+	"frame":  {"src"},
+	"embed":  {"src"},
+	"object": {"data"},
+	"source": {"src", "srcset"},
+	"track":  {"src"},
+	"video":  {"src", "poster"},
+	"audio":  {"src"},
+	"body":   {"background"},
+}
+
+// requisiteRels are the link relations whose targets are page requisites.
+var requisiteRels = map[string]bool{
+	"stylesheet":       true,
+	"icon":             true,
+	"shortcut":         true,
+	"apple-touch-icon": true,
+	"preload":          true,
+	"modulepreload":    true,
+	"manifest":         true,
+}
+
+var (
+	cssURLPattern     = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"\s]*))\s*\)`)
+	cssImportPattern  = regexp.MustCompile(`@import\s+(?:"([^"]*)"|'([^']*)')`)
+	metaRefreshPrefix = regexp.MustCompile(`(?i)^\s*\d*(?:\.\d*)?\s*[;,]?\s*(?:url\s*=\s*)?['"]?`)
+)
+
+// walkHTML calls visit with every http link in an HTML document, resolved
+// against baseURL or the document's <base href>, and returns the document
+// with the links replaced by the results. Tags without links and the text
+// between them are kept byte for byte, while <base> tags are dropped since
+// the links no longer depend on them.
+func walkHTML(content []byte, baseURL *url.URL, visit func(Link) string) []byte {
+	var buf bytes.Buffer
+	tokenizer := html.NewTokenizer(bytes.NewReader(content))
+	inStyle := false
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if tokenizer.Err() != io.EOF {
+				buf.Write(tokenizer.Raw())
+			}
+			return buf.Bytes()
+		}
+		raw := tokenizer.Raw()
+
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			inStyle = token.Data == "style" && tokenType == html.StartTagToken
+
+			if token.Data == "base" {
+				if href, ok := attrValue(token, "href"); ok {
+					if base := resolveURL(href, baseURL); base != "" {
+						baseURL, _ = url.Parse(base)
+					}
+					continue
+				}
+			}
+			if walkAttrs(&token, baseURL, visit) {
+				buf.WriteString(token.String())
+				continue
+			}
+		case html.EndTagToken:
+			inStyle = false
+		case html.TextToken:
+			if inStyle {
+				buf.Write(walkCSS(raw, baseURL, visit))
+				continue
+			}
+		}
+		buf.Write(raw)
+	}
+}
+
+func walkAttrs(token *html.Token, baseURL *url.URL, visit func(Link) string) bool {
+	requisite := true
+	switch token.Data {
+	case "a", "area":
+		requisite = false
+	case "link":
+		rel, _ := attrValue(*token, "rel")
+		requisite = false
+		for _, r := range strings.Fields(strings.ToLower(rel)) {
+			requisite = requisite || requisiteRels[r]
+		}
+	}
+
+	changed := false
+	for i, attr := range token.Attr {
+		var value string
+		switch {
+		case attr.Key == "style":
+			value = string(walkCSS([]byte(attr.Val), baseURL, visit))
+		case attr.Key == "content" && isMetaRefresh(*token):
+			value = walkMetaRefresh(attr.Val, baseURL, visit)
+		case !hasLinkAttr(token.Data, attr.Key):
+			continue
+		case attr.Key == "srcset":
+			value = walkSrcset(attr.Val, baseURL, visit)
+		default:
+			value = walkLink(attr.Val, baseURL, requisite, visit)
+		}
+		if value != attr.Val {
+			token.Attr[i].Val = value
+			changed = true
+		}
+	}
+	return changed
+}
+
+func attrValue(token html.Token, key string) (string, bool) {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+func hasLinkAttr(tag, key string) bool {
+	for _, attr := range linkAttrs[tag] {
+		if attr == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isMetaRefresh(token html.Token) bool {
+	equiv, _ := attrValue(token, "http-equiv")
+	return token.Data == "meta" && strings.EqualFold(equiv, "refresh")
+}
+
+// walkMetaRefresh handles the URL of a refresh such as "5; url=next.html".
+func walkMetaRefresh(content string, baseURL *url.URL, visit func(Link) string) string {
+	start := metaRefreshPrefix.FindStringIndex(content)
+	if start == nil || start[1] == len(content) {
+		return content
+	}
+	link := strings.TrimRight(content[start[1]:], "'\" \t")
+	end := start[1] + len(link)
+	return content[:start[1]] + walkLink(link, baseURL, false, visit) + content[end:]
+}
+
+// walkCSS calls visit with the targets of url() and @import in a
+// stylesheet, all of which are requisites.
+func walkCSS(content []byte, baseURL *url.URL, visit func(Link) string) []byte {
+	replace := func(pattern *regexp.Regexp, content []byte) []byte {
+		return pattern.ReplaceAllFunc(content, func(match []byte) []byte {
+			groups := pattern.FindSubmatchIndex(match)
+			for i := 2; i < len(groups); i += 2 {
+				if groups[i] < 0 {
+					continue
+				}
+				link := string(match[groups[i]:groups[i+1]])
+				var res []byte
+				res = append(res, match[:groups[i]]...)
+				res = append(res, walkLink(link, baseURL, true, visit)...)
+				return append(res, match[groups[i+1]:]...)
+			}
+			return match
+		})
+	}
+	return replace(cssImportPattern, replace(cssURLPattern, content))
+}
+
+func walkSrcset(srcset string, baseURL *url.URL, visit func(Link) string) string {
+	candidates := parseSrcset(srcset)
+	parts := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		parts[i] = walkLink(candidate.url, baseURL, true, visit)
+		if candidate.descriptor != "" {
+			parts[i] += " " + candidate.descriptor
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// walkLink resolves an http link and passes it to visit. Other links, such
+// as fragments and data URLs, are returned unchanged.
+func walkLink(link string, baseURL *url.URL, requisite bool, visit func(Link) string) string {
+	absoluteURL := resolveURL(strings.TrimSpace(link), baseURL)
+	if absoluteURL == "" {
+		return link
+	}
+	u, err := url.Parse(absoluteURL)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+		return link
+	}
+	return visit(Link{URL: absoluteURL, Requisite: requisite})
+}
+
+type srcsetCandidate struct {
+	url        string
+	descriptor string
+}
+
+// parseSrcset splits a srcset attribute into its image candidates.
+func parseSrcset(srcset string) []srcsetCandidate {
+	var candidates []srcsetCandidate
+	for rest := srcset; ; {
+		rest = strings.TrimLeft(rest, " \t\n\r\f,")
+		if rest == "" {
+			return candidates
+		}
+
+		end := strings.IndexAny(rest, " \t\n\r\f")
+		if end < 0 {
+			end = len(rest)
+		}
+		candidate := srcsetCandidate{url: rest[:end]}
+		rest = rest[end:]
+
+		if strings.HasSuffix(candidate.url, ",") {
+			candidate.url = strings.TrimRight(candidate.url, ",")
+		} else {
+			end = strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			candidate.descriptor = strings.TrimSpace(rest[:end])
+			rest = rest[end:]
+		}
+		candidates = append(candidates, candidate)
+	}
+}
@@ -0,0 +1,21 @@
+package parser
+
+import (
+	"net/url"
+)
+
+// RewriteHTML returns content with every link passed through rewrite as an
+// absolute URL.
+func RewriteHTML(content []byte, baseURL *url.URL, rewrite func(string) string) []byte {
+	return walkHTML(content, baseURL, func(link Link) string {
+		return rewrite(link.URL)
+	})
+}
+
+// RewriteCSS returns a stylesheet with the targets of url() and @import
+// passed through rewrite as absolute URLs.
+func RewriteCSS(content []byte, baseURL *url.URL, rewrite func(string) string) []byte {
+	return walkCSS(content, baseURL, func(link Link) string {
+		return rewrite(link.URL)
+	})
+}
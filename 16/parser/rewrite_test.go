@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bracket(link string) string {
+	return "[" + link + "]"
+}
+
+func TestRewriteHTML(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{`<!DOCTYPE html><html><body><p>Hi</p></body></html>`, `<!DOCTYPE html><html><body><p>Hi</p></body></html>`},
+		{`<a href="page.html#s">x</a>`, `<a href="[http://example.com/dir/page.html#s]">x</a>`},
+		{`<A HREF='/abs'>x</A>`, `<a href="[http://example.com/abs]">x</A>`},
+		{`<a href="#top">x</a> <a href="mailto:me@example.com">m</a>`, `<a href="#top">x</a> <a href="mailto:me@example.com">m</a>`},
+		{`<a name="x">x</a>`, `<a name="x">x</a>`},
+		{`<img src="a.png" alt="A &amp; B">`, `<img src="[http://example.com/dir/a.png]" alt="A &amp; B">`},
+		{`<img srcset="a.png 1x, b.png 2x">`, `<img srcset="[http://example.com/dir/a.png] 1x, [http://example.com/dir/b.png] 2x">`},
+		{`<img src="data:image/png;base64,AAAA">`, `<img src="data:image/png;base64,AAAA">`},
+		{`<link rel="stylesheet" href="https://cdn.example.org/s.css"/>`, `<link rel="stylesheet" href="[https://cdn.example.org/s.css]"/>`},
+		{`<style>body { background: url('bg.png') }</style>`, `<style>body { background: url('[http://example.com/dir/bg.png]') }</style>`},
+		{`<div style="background: url(bg.png)">x</div>`, `<div style="background: url([http://example.com/dir/bg.png])">x</div>`},
+		{`<p>url(bg.png)</p>`, `<p>url(bg.png)</p>`},
+		{`<script>var s = "<a href='x'>";</script>`, `<script>var s = "<a href='x'>";</script>`},
+		{`<head><base href="/other/"></head><img src="a.png">`, `<head></head><img src="[http://example.com/other/a.png]">`},
+		{`<meta http-equiv="refresh" content="0; URL='next.html'">`, `<meta http-equiv="refresh" content="0; URL=&#39;[http://example.com/dir/next.html]&#39;">`},
+		{`<video poster="p.jpg"></video>`, `<video poster="[http://example.com/dir/p.jpg]"></video>`},
+	}
+
+	base, err := url.Parse("http://example.com/dir/index.html")
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, string(RewriteHTML([]byte(test.content), base, bracket)), test.content)
+	}
+}
+
+func TestRewriteCSS(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{`a { background: url(img/a.png) }`, `a { background: url([http://example.com/css/img/a.png]) }`},
+		{`a { background: url( "../b.png" ) }`, `a { background: url( "[http://example.com/b.png]" ) }`},
+		{`@import "print.css"; @import url('/base.css');`, `@import "[http://example.com/css/print.css]"; @import url('[http://example.com/base.css]');`},
+		{`a { background: url(data:image/gif;base64,R0lG) }`, `a { background: url(data:image/gif;base64,R0lG) }`},
+		{`a { color: red }`, `a { color: red }`},
+	}
+
+	base, err := url.Parse("http://example.com/css/style.css")
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, string(RewriteCSS([]byte(test.content), base, bracket)), test.content)
+	}
+}
+
+func TestParseSrcset(t *testing.T) {
+	tests := []struct {
+		srcset string
+		want   []srcsetCandidate
+	}{
+		{"a.png", []srcsetCandidate{{"a.png", ""}}},
+		{"a.png 1x, b.png 2x", []srcsetCandidate{{"a.png", "1x"}, {"b.png", "2x"}}},
+		{" a.png 480w,b.png  800w ", []srcsetCandidate{{"a.png", "480w"}, {"b.png", "800w"}}},
+		{"a.png, b.png 2x", []srcsetCandidate{{"a.png", ""}, {"b.png", "2x"}}},
+		{"img,1.png 1x", []srcsetCandidate{{"img,1.png", "1x"}}},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, parseSrcset(test.srcset), test.srcset)
+	}
+}
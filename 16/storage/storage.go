@@ -2,17 +2,21 @@ package storage
 
 import (
 	"net/url"
+	"path"
+	"strings"
 	"sync"
 )
 
+// URLStorage records the visited URLs with the shallowest crawl depth each
+// was reached at.
 type URLStorage struct {
-	visited map[string]bool
+	visited map[string]int
 	mutex   sync.RWMutex
 }
 
 func NewURLStorage() *URLStorage {
 	return &URLStorage{
-		visited: make(map[string]bool),
+		visited: make(map[string]int),
 	}
 }
 
@@ -21,7 +25,8 @@ func (u *URLStorage) Has(urlStr string) bool {
 
 	u.mutex.RLock()
 	defer u.mutex.RUnlock()
-	return u.visited[norm]
+	_, ok := u.visited[norm]
+	return ok
 }
 
 func (u *URLStorage) Add(urlStr string) {
@@ -29,7 +34,33 @@ func (u *URLStorage) Add(urlStr string) {
 
 	u.mutex.Lock()
 	defer u.mutex.Unlock()
-	u.visited[norm] = true
+	u.visited[norm] = 0
+}
+
+// Depth returns the shallowest depth urlStr was reached at.
+func (u *URLStorage) Depth(urlStr string) (int, bool) {
+	norm := u.NormalizeURL(urlStr)
+
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+	depth, ok := u.visited[norm]
+	return depth, ok
+}
+
+// Visit records that urlStr was reached at depth. It reports whether the
+// URL is new, and whether it was only reached deeper before, so that its
+// links are worth following again from the shallower depth.
+func (u *URLStorage) Visit(urlStr string, depth int) (first, shallower bool) {
+	norm := u.NormalizeURL(urlStr)
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	old, ok := u.visited[norm]
+	if ok && old <= depth {
+		return false, false
+	}
+	u.visited[norm] = depth
+	return !ok, ok
 }
 
 func (s *URLStorage) NormalizeURL(urlStr string) string {
@@ -40,7 +71,18 @@ func (s *URLStorage) NormalizeURL(urlStr string) string {
 
 	urlNorm.Fragment = ""
 
+	// A directory and its index.html are the same file in the mirror.
+	if path.Base(urlNorm.Path) == "index.html" {
+		urlNorm.Path = strings.TrimSuffix(urlNorm.Path, "index.html")
+	}
+	if urlNorm.Path == "" && urlNorm.Host != "" {
+		urlNorm.Path = "/"
+	}
+
 	if urlNorm.Host != "" {
+		if urlNorm.RawQuery != "" {
+			return urlNorm.Host + urlNorm.Path + "?" + urlNorm.RawQuery
+		}
 		return urlNorm.Host + urlNorm.Path
 	}
 